@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"TF/internal/cli"
+	"TF/internal/eval"
+	"TF/internal/ml"
+)
+
+// report: todo lo evaluado en una corrida (cada sección es opcional)
+type report struct {
+	Rating      []eval.RatingResult      `json:"rating,omitempty"`
+	Ranking     []eval.RankingResult     `json:"ranking,omitempty"`
+	Beyond      []eval.BeyondResult      `json:"beyond,omitempty"`
+	Fairness    []eval.FairnessResult    `json:"fairness,omitempty"`
+	Calibration []eval.CalibrationResult `json:"calibration,omitempty"`
+	Sequential  []ml.LeaveLastOutResult  `json:"sequential,omitempty"`
+
+	Significance []eval.Comparison `json:"significance,omitempty"`
+	correction   eval.Correction
+}
+
+func main() {
+	format := flag.String("format", "text", "formato de salida: text, csv o json")
+	metrics := flag.String("metrics", "rating,ranking,beyond", "qué evaluar, separado por comas: rating, ranking, beyond, fairness, calibration, sequential")
+	split := flag.String("split", "random", "partición train/test: random (fracción -test de cada usuario) o leave-last-out (el último rating de cada usuario)")
+	testFraction := flag.Float64("test", 0.2, "fracción de ratings de cada usuario para test (split random)")
+	seed := flag.Int64("seed", 42, "semilla del split train/test y de los negativos")
+	workers := flag.Int("workers", 0, "goroutines para evaluar (0 = NumCPU)")
+	neighborK := flag.Int("k", 30, "vecinos de los modelos KNN")
+	cutoffs := flag.String("cutoffs", "5,10,20", "cortes k de las métricas de ranking")
+	threshold := flag.Float64("threshold", 4, "rating de test (estrellas) para considerar relevante un item")
+	negatives := flag.Int("negatives", 0, "ranking con N negativos al azar por usuario (0 = todo el catálogo)")
+	topN := flag.Int("topn", 10, "largo de las listas para las métricas beyond y sequential")
+	history := flag.Int("history", 5, "últimas películas de train que reciben los modelos secuenciales")
+	sample := flag.Int("sample", 500, "usuarios de la muestra para las métricas beyond (0 = todos)")
+	ild := flag.String("ild", "cosine", "similitud para la diversidad intra-lista: cosine, pearson, jaccard o genre")
+	compare := flag.String("compare", "rmse,ndcg@10", "métricas por usuario a comparar contra -baseline (vacío = no comparar)")
+	baseline := flag.String("baseline", "", "modelo de referencia para las comparaciones (vacío = el primero)")
+	correction := flag.String("correction", "holm", "corrección por comparaciones múltiples: none, holm o bonferroni")
+	bootstrap := flag.Int("bootstrap", 1000, "remuestreos del intervalo de confianza bootstrap")
+	debias := flag.String("debias", "", "agregar cada modelo con un re-ranker de des-sesgo: xquad o ipw")
+	debiasWeight := flag.Float64("debias-weight", 0.5, "λ de xquad o β de ipw")
+	calibrate := flag.Float64("calibrate", 0, "agregar cada modelo re-rankeado por calibración de géneros con este λ (0 = no)")
+	halfLife := flag.Float64("half-life", 0, "agregar los modelos KNN con decaimiento temporal exponencial de esta vida media en días (0 = no)")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Uso: go run ./cmd/eval [-format text|csv|json] [-metrics rating,ranking,beyond] [-split random|leave-last-out] [-test 0.2] [-seed 42] [-workers N] [-k 30] [-cutoffs 5,10,20] [-threshold 4] [-negatives N] [-topn 10] [-history 5] [-sample 500] [-ild cosine] [-compare rmse,ndcg@10] [-baseline modelo] [-correction holm] [-bootstrap 1000] [-debias xquad|ipw] [-debias-weight 0.5] [-calibrate λ] [-half-life días] [10|20|25]")
+		return
+	}
+	corr, err := eval.ParseCorrection(*correction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *split != "random" && *split != "leave-last-out" {
+		log.Fatalf("-split: partición desconocida %q (usa random o leave-last-out)", *split)
+	}
+	datasetPath, err := cli.DatasetPath(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ks, err := cli.ParseInts(*cutoffs)
+	if err != nil {
+		log.Fatalf("-cutoffs: %v", err)
+	}
+	want := make(map[string]bool)
+	for _, m := range strings.Split(*metrics, ",") {
+		want[strings.TrimSpace(m)] = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// el progreso va a stderr para que stdout sea sólo el reporte (csv/json)
+	logf := func(format string, args ...any) { fmt.Fprintf(os.Stderr, format+"\n", args...) }
+
+	ds, err := ml.LoadDataset(datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var train, test *ml.Dataset
+	if *split == "leave-last-out" {
+		train, test = ds.LeaveLastOut()
+	} else {
+		train, test = ds.Split(*testFraction, *seed)
+	}
+	logf("Dataset %s: %d ratings (train %d / test %d)", datasetPath, ds.NumRatings(), train.NumRatings(), test.NumRatings())
+
+	newModels := func() []ml.Recommender {
+		return []ml.Recommender{
+			ml.NewItemKNN(ml.CosineSim, *neighborK),
+			ml.NewItemKNN(ml.PearsonSim, *neighborK),
+			ml.NewItemKNN(ml.JaccardSim, *neighborK),
+			ml.NewUserKNN(ml.CosineSim, *neighborK),
+			ml.NewUserKNN(ml.PearsonSim, *neighborK),
+			ml.NewUserKNN(ml.JaccardSim, *neighborK),
+			ml.NewSlopeOne(ml.WeightedSlopeOne),
+			ml.NewPopularity(),
+		}
+	}
+	models := newModels()
+	if *debias != "" {
+		// instancias nuevas: cada variante se entrena por separado
+		for _, m := range newModels() {
+			rr, err := debiasReranker(*debias, *debiasWeight)
+			if err != nil {
+				log.Fatalf("-debias: %v", err)
+			}
+			models = append(models, ml.WithReranker(m, rr))
+		}
+	}
+
+	if *halfLife > 0 {
+		hl := time.Duration(*halfLife * float64(24*time.Hour))
+		for _, m := range newModels() {
+			switch knn := m.(type) {
+			case *ml.ItemKNN:
+				knn.Decay = ml.NewExponentialDecay(hl)
+			case *ml.UserKNN:
+				knn.Decay = ml.NewExponentialDecay(hl)
+			default:
+				continue
+			}
+			models = append(models, m)
+		}
+	}
+
+	// el catálogo (géneros) sólo se carga si algo lo usa
+	var cat *ml.Catalog
+	if want["calibration"] || *calibrate > 0 {
+		cat, err = ml.LoadCatalog(filepath.Join(filepath.Dir(datasetPath), "movies.csv"), "")
+		if err != nil {
+			log.Fatalf("catálogo: %v", err)
+		}
+	}
+	if *calibrate > 0 {
+		for _, m := range newModels() {
+			models = append(models, ml.WithReranker(m, ml.NewCalibrated(cat, *calibrate)))
+		}
+	}
+
+	rankOpts := eval.RankingOptions{
+		Cutoffs:   ks,
+		Threshold: ml.NormalizeRating(*threshold),
+		Negatives: *negatives,
+		Seed:      *seed,
+		Workers:   *workers,
+	}
+	if *negatives > 0 {
+		rankOpts.Protocol = eval.SampledNegatives
+	}
+
+	beyondOpts := eval.BeyondOptions{
+		K:          *topN,
+		SampleSize: *sample,
+		Seed:       *seed,
+		Threshold:  ml.NormalizeRating(*threshold),
+		Workers:    *workers,
+	}
+	if want["beyond"] {
+		beyondOpts.Sim, err = similarityByName(*ild, train, datasetPath)
+		if err != nil {
+			log.Fatalf("-ild: %v", err)
+		}
+		beyondOpts.SimName = *ild
+	}
+
+	var rep report
+	for _, m := range models {
+		if want["rating"] {
+			logf("Evaluando %s (rating)...", m.Name())
+			res, err := eval.EvaluateRating(ctx, m, train, test, *workers)
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Rating = append(rep.Rating, res)
+		}
+		if want["ranking"] {
+			logf("Evaluando %s (ranking %s)...", m.Name(), rankOpts.Protocol)
+			res, err := eval.EvaluateRanking(ctx, m, train, test, rankOpts)
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Ranking = append(rep.Ranking, res)
+		}
+		if want["beyond"] {
+			logf("Evaluando %s (beyond)...", m.Name())
+			res, err := eval.EvaluateBeyond(ctx, m, train, test, beyondOpts)
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Beyond = append(rep.Beyond, res)
+		}
+		if want["fairness"] {
+			logf("Evaluando %s (fairness)...", m.Name())
+			res, err := eval.EvaluateFairness(ctx, m, train, test, eval.FairnessOptions{
+				K:          *topN,
+				SampleSize: *sample,
+				Seed:       *seed,
+				Threshold:  ml.NormalizeRating(*threshold),
+				Workers:    *workers,
+			})
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Fairness = append(rep.Fairness, res)
+		}
+		if want["calibration"] {
+			logf("Evaluando %s (calibration)...", m.Name())
+			res, err := eval.EvaluateCalibration(ctx, m, train, test, eval.CalibrationOptions{
+				K:          *topN,
+				SampleSize: *sample,
+				Seed:       *seed,
+				Catalog:    cat,
+				Workers:    *workers,
+			})
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Calibration = append(rep.Calibration, res)
+		}
+	}
+
+	// los modelos de siguiente película se evalúan siempre con leave-last-out,
+	// sea cual sea -split: la película a adivinar es la última que vio cada usuario
+	if want["sequential"] {
+		seqTrain, seqTest := ds.LeaveLastOut()
+		for _, m := range []ml.SequentialRecommender{ml.NewMarkov(1), ml.NewSessionKNN(*neighborK)} {
+			logf("Evaluando %s (sequential)...", m.Name())
+			res, err := ml.EvaluateLeaveLastOut(ctx, m, seqTrain, seqTest, *history, *topN)
+			if err != nil {
+				log.Fatalf("%s: %v", m.Name(), err)
+			}
+			rep.Sequential = append(rep.Sequential, res)
+		}
+	}
+
+	if *compare != "" {
+		rep.correction = corr
+		rep.Significance, err = significance(rep, models, *baseline, strings.Split(*compare, ","), eval.CompareOptions{
+			Bootstrap:  *bootstrap,
+			Seed:       *seed,
+			Correction: corr,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *format == "json" {
+		err = eval.WriteJSON(os.Stdout, rep)
+	} else {
+		err = writeTables(*format, rep)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeTables: una tabla por sección, separadas por una línea en blanco
+func writeTables(format string, rep report) error {
+	var tables []eval.Table
+	if len(rep.Rating) > 0 {
+		tables = append(tables, eval.RatingTable(rep.Rating))
+	}
+	if len(rep.Ranking) > 0 {
+		tables = append(tables, eval.RankingTable(rep.Ranking))
+	}
+	if len(rep.Beyond) > 0 {
+		tables = append(tables, eval.BeyondTable(rep.Beyond))
+	}
+	if len(rep.Fairness) > 0 {
+		tiers, groups := eval.FairnessTables(rep.Fairness)
+		tables = append(tables, tiers, groups)
+	}
+	if len(rep.Calibration) > 0 {
+		tables = append(tables, eval.CalibrationTable(rep.Calibration))
+	}
+	if len(rep.Sequential) > 0 {
+		tables = append(tables, eval.SequentialTable(rep.Sequential))
+	}
+	if len(rep.Significance) > 0 {
+		tables = append(tables, eval.SignificanceTable(rep.Significance, rep.correction))
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Println()
+		}
+		var err error
+		if format == "csv" {
+			err = eval.WriteCSV(os.Stdout, t)
+		} else {
+			err = eval.WriteText(os.Stdout, t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// significance: compara cada modelo contra el de referencia en las métricas por
+// usuario pedidas (las de rating, ranking y calibración de la corrida). Las que
+// no se evaluaron en esta corrida se saltean con un aviso en stderr.
+func significance(rep report, models []ml.Recommender, baseline string, metrics []string, opts eval.CompareOptions) ([]eval.Comparison, error) {
+	perUser := make([]eval.PerUser, len(models))
+	for i := range models {
+		perUser[i] = make(eval.PerUser)
+		if i < len(rep.Rating) {
+			maps.Copy(perUser[i], rep.Rating[i].PerUser)
+		}
+		if i < len(rep.Ranking) {
+			maps.Copy(perUser[i], rep.Ranking[i].PerUser)
+		}
+		if i < len(rep.Calibration) {
+			maps.Copy(perUser[i], rep.Calibration[i].PerUser)
+		}
+	}
+	var evaluated []string
+	for _, m := range metrics {
+		m = strings.TrimSpace(m)
+		if _, ok := perUser[0][m]; !ok {
+			fmt.Fprintf(os.Stderr, "-compare: métrica %q no evaluada en esta corrida, se omite\n", m)
+			continue
+		}
+		evaluated = append(evaluated, m)
+	}
+	if len(evaluated) == 0 {
+		return nil, nil
+	}
+
+	base := 0
+	if baseline != "" {
+		base = -1
+		for i, m := range models {
+			if m.Name() == baseline {
+				base = i
+			}
+		}
+		if base < 0 {
+			return nil, fmt.Errorf("-baseline: modelo desconocido %q", baseline)
+		}
+	}
+
+	var names []string
+	var others []eval.PerUser
+	for i, m := range models {
+		if i != base {
+			names = append(names, m.Name())
+			others = append(others, perUser[i])
+		}
+	}
+	return eval.CompareAll(evaluated, models[base].Name(), perUser[base], names, others, opts), nil
+}
+
+// debiasReranker: re-ranker de sesgo de popularidad por nombre
+func debiasReranker(name string, weight float64) (ml.Reranker, error) {
+	switch name {
+	case "xquad":
+		return ml.NewXQuAD(weight), nil
+	case "ipw":
+		return ml.NewInversePropensity(weight), nil
+	default:
+		return nil, fmt.Errorf("re-ranker desconocido %q (usa xquad o ipw)", name)
+	}
+}
+
+// similarityByName: similitud item-item para la diversidad intra-lista. Las de
+// ratings se calculan sobre train; genre lee movies.csv junto al ratings.csv.
+func similarityByName(name string, train *ml.Dataset, datasetPath string) (ml.ItemSimilarity, error) {
+	switch name {
+	case "cosine":
+		return ml.NewRatingSimilarity(train, ml.CosineSim), nil
+	case "pearson":
+		return ml.NewRatingSimilarity(train, ml.PearsonSim), nil
+	case "jaccard":
+		return ml.NewRatingSimilarity(train, ml.JaccardSim), nil
+	case "genre":
+		cat, err := ml.LoadCatalog(filepath.Join(filepath.Dir(datasetPath), "movies.csv"), "")
+		if err != nil {
+			return nil, err
+		}
+		return ml.GenreSimilarity(cat), nil
+	default:
+		return nil, fmt.Errorf("similitud desconocida %q (usa cosine, pearson, jaccard o genre)", name)
+	}
+}
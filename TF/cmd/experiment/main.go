@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"TF/internal/bandit"
+	"TF/internal/cli"
+	"TF/internal/eval"
+	"TF/internal/experiment"
+	"TF/internal/ml"
+)
+
+const usage = `Uso:
+  go run ./cmd/experiment analyze [-confidence 0.95] [-format text|csv|json] eventos.jsonl
+  go run ./cmd/experiment bandit [-base item-cosine] [-policies greedy,epsilon,ucb1,thompson] [-epsilon 0.1] [-c 1] [-prior 2] [-cold 0] [-topn 10] [-seed 42] [-format text|csv|json] eventos.jsonl [10|20|25]
+  go run ./cmd/experiment replay [-mode interleave|ab] [-a item-cosine] [-b user-cosine] [-name exp] [-users 500] [-topn 10] [-threshold 4] [-seed 42] [-out eventos.jsonl] [10|20|25]
+
+Modelos (replay y -base de bandit): item-cosine, item-pearson, item-jaccard, user-cosine, user-pearson, user-jaccard, slopeone, popularity`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		return
+	}
+	var err error
+	switch os.Args[1] {
+	case "analyze":
+		err = analyze(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	case "bandit":
+		err = banditReplay(os.Args[2:])
+	default:
+		fmt.Println(usage)
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// analyze: tasas de victoria de los intercalados y CTR de los A/B de un log
+func analyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	confidence := fs.Float64("confidence", 0.95, "nivel de los intervalos de confianza")
+	format := fs.String("format", "text", "formato de salida: text, csv o json")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Println(usage)
+		return nil
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	events, err := experiment.ReadEvents(f)
+	if err != nil {
+		return err
+	}
+	res := experiment.Analyze(events, *confidence)
+	if *format == "json" {
+		return eval.WriteJSON(os.Stdout, res)
+	}
+
+	pct := strconv.FormatFloat(res.Confidence*100, 'g', -1, 64) + "%"
+	var tables []eval.Table
+	if len(res.Interleaving) > 0 {
+		t := eval.Table{
+			Title:  "Interleaving (team-draft), IC " + pct,
+			Header: []string{"experimento", "A", "B", "impresiones", "con_clicks", "gana_A", "gana_B", "empates", "win_rate_A", "ic_bajo", "ic_alto", "preferencia", "p_valor"},
+		}
+		for _, r := range res.Interleaving {
+			t.Rows = append(t.Rows, []string{
+				r.Experiment, r.A, r.B,
+				strconv.Itoa(r.Impressions),
+				strconv.Itoa(r.WithClicks),
+				strconv.Itoa(r.WinsA),
+				strconv.Itoa(r.WinsB),
+				strconv.Itoa(r.Ties),
+				ftoa(r.WinRateA),
+				ftoa(r.CILow),
+				ftoa(r.CIHigh),
+				ftoa(r.Preference),
+				strconv.FormatFloat(r.PValue, 'g', 4, 64),
+			})
+		}
+		tables = append(tables, t)
+	}
+	if len(res.Arms) > 0 {
+		t := eval.Table{
+			Title:  "A/B por variante, IC " + pct,
+			Header: []string{"experimento", "variante", "usuarios", "impresiones", "con_clicks", "clicks", "ctr", "ic_bajo", "ic_alto"},
+		}
+		for _, r := range res.Arms {
+			t.Rows = append(t.Rows, []string{
+				r.Experiment, r.Arm,
+				strconv.Itoa(r.Users),
+				strconv.Itoa(r.Impressions),
+				strconv.Itoa(r.Clicked),
+				strconv.Itoa(r.Clicks),
+				ftoa(r.CTR),
+				ftoa(r.CILow),
+				ftoa(r.CIHigh),
+			})
+		}
+		tables = append(tables, t)
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Println()
+		}
+		if *format == "csv" {
+			err = eval.WriteCSV(os.Stdout, t)
+		} else {
+			err = eval.WriteText(os.Stdout, t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if res.Orphans > 0 {
+		fmt.Fprintf(os.Stderr, "%d clicks sin impresión en el log\n", res.Orphans)
+	}
+	return nil
+}
+
+// replay: simulación offline de un experimento. Entrena A y B sobre train, le
+// muestra a cada usuario de la muestra la lista intercalada (o la de su variante
+// en modo ab) y hace click en lo que calificó con >= threshold en test.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	mode := fs.String("mode", "interleave", "interleave (team-draft) o ab (una variante por usuario)")
+	nameA := fs.String("a", "item-cosine", "modelo A")
+	nameB := fs.String("b", "user-cosine", "modelo B")
+	expName := fs.String("name", "replay", "nombre del experimento (también es la sal del hash)")
+	users := fs.Int("users", 500, "usuarios de test a simular (0 = todos)")
+	topN := fs.Int("topn", 10, "largo de la lista mostrada")
+	neighborK := fs.Int("k", 30, "vecinos de los modelos KNN")
+	threshold := fs.Float64("threshold", 4, "rating de test (estrellas) que cuenta como click")
+	testFraction := fs.Float64("test", 0.2, "fracción de ratings de cada usuario para test")
+	seed := fs.Int64("seed", 42, "semilla del split y de la muestra")
+	out := fs.String("out", "", "archivo de eventos (vacío = stdout)")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Println(usage)
+		return nil
+	}
+	if *mode != "interleave" && *mode != "ab" {
+		return fmt.Errorf("-mode: modo desconocido %q (usa interleave o ab)", *mode)
+	}
+	datasetPath, err := cli.DatasetPath(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	recA, err := cli.ModelByName(*nameA, *neighborK)
+	if err != nil {
+		return fmt.Errorf("-a: %w", err)
+	}
+	recB, err := cli.ModelByName(*nameB, *neighborK)
+	if err != nil {
+		return fmt.Errorf("-b: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ds, err := ml.LoadDataset(datasetPath)
+	if err != nil {
+		return err
+	}
+	train, test := ds.Split(*testFraction, *seed)
+	fmt.Fprintf(os.Stderr, "Dataset %s: train %d / test %d ratings\n", datasetPath, train.NumRatings(), test.NumRatings())
+	for _, rec := range []ml.Recommender{recA, recB} {
+		fmt.Fprintf(os.Stderr, "Entrenando %s...\n", rec.Name())
+		if err := rec.Fit(train); err != nil {
+			return fmt.Errorf("%s: %w", rec.Name(), err)
+		}
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	logger := experiment.NewLogger(w)
+	exp, err := experiment.NewExperiment(*expName, *nameA, *nameB)
+	if err != nil {
+		return err
+	}
+	relevant := ml.NormalizeRating(*threshold)
+
+	for _, u := range sample(test, *users, *seed) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		opts := ml.RecommendOptions{TopK: *topN}
+		var id string
+		var shown []int
+		if *mode == "interleave" {
+			a, err := recA.Recommend(ctx, u, opts)
+			if err != nil {
+				return err
+			}
+			b, err := recB.Recommend(ctx, u, opts)
+			if err != nil {
+				return err
+			}
+			il := experiment.TeamDraft(a, b, *topN, experiment.NewRand(*expName, u))
+			if len(il.Items) == 0 {
+				continue
+			}
+			if id, err = logger.Interleaved(*expName, u, *nameA, *nameB, il); err != nil {
+				return err
+			}
+			for _, it := range il.Items {
+				shown = append(shown, it.MovieID)
+			}
+		} else {
+			arm, rec := *nameA, recA
+			if exp.Assign(u) == *nameB {
+				arm, rec = *nameB, recB
+			}
+			recs, err := rec.Recommend(ctx, u, opts)
+			if err != nil {
+				return err
+			}
+			for _, it := range recs {
+				shown = append(shown, it.MovieID)
+			}
+			if id, err = logger.Shown(*expName, u, arm, shown); err != nil {
+				return err
+			}
+		}
+		for pos, it := range shown {
+			if r, ok := test.UserRatings[u][it]; ok && r >= relevant {
+				if err := logger.Click(*expName, id, u, it, pos+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return logger.Flush()
+}
+
+// banditReplay: CTR estimado de cada política de exploración sobre un log de
+// eventos, con el modelo base entrenado sobre el mismo train que usó replay
+func banditReplay(args []string) error {
+	fs := flag.NewFlagSet("bandit", flag.ExitOnError)
+	baseName := fs.String("base", "item-cosine", "recomendador base")
+	policies := fs.String("policies", "greedy,epsilon,ucb1,thompson", "políticas a comparar")
+	epsilon := fs.Float64("epsilon", 0.1, "ε de epsilon-greedy")
+	c := fs.Float64("c", 1, "C de ucb1")
+	prior := fs.Float64("prior", bandit.DefaultPriorWeight, "peso de la relevancia del modelo base como prior")
+	coldRatings := fs.Int("cold", 0, "películas con hasta esta cantidad de ratings cuentan como nuevas")
+	topN := fs.Int("topn", 10, "largo de la lista de la política")
+	neighborK := fs.Int("k", 30, "vecinos de los modelos KNN")
+	testFraction := fs.Float64("test", 0.2, "fracción de test (la misma que en replay)")
+	seed := fs.Int64("seed", 42, "semilla del split y de las políticas")
+	format := fs.String("format", "text", "formato de salida: text, csv o json")
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fmt.Println(usage)
+		return nil
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	events, err := experiment.ReadEvents(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	datasetPath, err := cli.DatasetPath(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	var pols []bandit.Policy
+	for _, name := range strings.Split(*policies, ",") {
+		name = strings.TrimSpace(name)
+		param := *epsilon
+		if name == "ucb1" {
+			param = *c
+		}
+		p, err := bandit.ParsePolicy(name, param)
+		if err != nil {
+			return err
+		}
+		pols = append(pols, p)
+	}
+	base, err := cli.ModelByName(*baseName, *neighborK)
+	if err != nil {
+		return fmt.Errorf("-base: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ds, err := ml.LoadDataset(datasetPath)
+	if err != nil {
+		return err
+	}
+	cat, err := ml.LoadCatalog(filepath.Join(filepath.Dir(datasetPath), "movies.csv"), "")
+	if err != nil {
+		return fmt.Errorf("catálogo: %w", err)
+	}
+	train, _ := ds.Split(*testFraction, *seed)
+	fmt.Fprintf(os.Stderr, "Entrenando %s...\n", base.Name())
+	if err := base.Fit(train); err != nil {
+		return err
+	}
+
+	var results []bandit.ReplayResult
+	for _, p := range pols {
+		ex := bandit.NewExplorer(p, *seed)
+		ex.PriorWeight = *prior
+		ex.Catalog = cat
+		ex.ColdRatings = *coldRatings
+		// base ya está entrenado: sólo falta el explorador
+		if err := ex.Fit(train); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Replay %s (%d películas nuevas)...\n", p.Name(), len(ex.Cold()))
+		res, err := bandit.Replay(ctx, ml.WithReranker(base, ex), ex, events, *topN)
+		if err != nil {
+			return err
+		}
+		results = append(results, res)
+	}
+
+	if *format == "json" {
+		return eval.WriteJSON(os.Stdout, results)
+	}
+	t := eval.Table{
+		Title:  "Replay de políticas de exploración sobre " + base.Name(),
+		Header: []string{"política", "impresiones", "lugares", "coincidencias", "clicks", "ctr", "ctr_log", "nuevas_mostradas"},
+	}
+	for _, r := range results {
+		t.Rows = append(t.Rows, []string{
+			r.Policy,
+			strconv.Itoa(r.Impressions),
+			strconv.Itoa(r.Slots),
+			strconv.Itoa(r.Matched),
+			strconv.Itoa(r.Clicks),
+			ftoa(r.CTR),
+			ftoa(r.LoggedCTR),
+			strconv.Itoa(r.NewShown),
+		})
+	}
+	if *format == "csv" {
+		return eval.WriteCSV(os.Stdout, t)
+	}
+	return eval.WriteText(os.Stdout, t)
+}
+
+// sample: n usuarios de test al azar con semilla (n <= 0 -> todos), ordenados
+func sample(ds *ml.Dataset, n int, seed int64) []int {
+	users := make([]int, 0, len(ds.UserRatings))
+	for u := range ds.UserRatings {
+		users = append(users, u)
+	}
+	sort.Ints(users)
+	if n > 0 && n < len(users) {
+		rng := rand.New(rand.NewSource(seed))
+		rng.Shuffle(len(users), func(i, j int) { users[i], users[j] = users[j], users[i] })
+		users = users[:n]
+		sort.Ints(users)
+	}
+	return users
+}
+
+func ftoa(x float64) string {
+	return strconv.FormatFloat(x, 'f', 4, 64)
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
+	"TF/internal/bandit"
 	"TF/internal/ml"
 )
 
@@ -20,11 +26,18 @@ func banner(title string) {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Uso: go run cmd/node/main.go [10|20|25]")
+	explain := flag.Bool("explain", false, "mostrar por qué se recomendó cada película")
+	asJSON := flag.Bool("json", false, "con -explain, imprimir las explicaciones en JSON")
+	userFlag := flag.Int("user", 1, "userId objetivo")
+	topKFlag := flag.Int("topk", 10, "cuántas recomendaciones pedir")
+	neighborsFlag := flag.Int("neighbors", 30, "vecinos por candidato (ver cmd/tune para elegirlo)")
+	exploreFlag := flag.String("explore", "", "mostrar además la lista con exploración: epsilon, ucb1 o thompson")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Uso: go run cmd/node/main.go [-explain] [-json] [-user 1] [-topk 10] [-neighbors 30] [-explore ucb1] [10|20|25]")
 		return
 	}
-	size := os.Args[1]
+	size := flag.Arg(0)
 
 	var datasetPath string
 	switch size {
@@ -38,6 +51,10 @@ func main() {
 		log.Fatalf("Tamaño no válido: %s (usa 10, 20 o 25)", size)
 	}
 
+	// Ctrl+C cancela la recomendación en curso sin esperar a que termine el scoring
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	banner("Cargando dataset")
 	ds, err := ml.LoadDataset(datasetPath)
 	if err != nil {
@@ -49,9 +66,9 @@ func main() {
 	//---------------------------------------------
 	// CONFIGURACIÓN EXPERIMENTO
 	//---------------------------------------------
-	userID := 1
-	topK := 10
-	neighborK := 30
+	userID := *userFlag
+	topK := *topKFlag
+	neighborK := *neighborsFlag
 	metrics := []ml.SimMetric{ml.CosineSim, ml.PearsonSim, ml.JaccardSim}
 
 	//---------------------------------------------
@@ -64,10 +81,21 @@ func main() {
 	for _, metric := range metrics {
 		fmt.Printf("==> Métrica: %s\n", metricName(metric))
 
+		// el índice se construye una sola vez y se reutiliza en todas las corridas
+		model := ml.NewItemKNN(metric, neighborK)
+		if err := model.Fit(ds); err != nil {
+			log.Fatal(err)
+		}
+		opts := ml.RecommendOptions{TopK: topK}
+
 		// SECUENCIAL
+		model.Workers = 1
 		startSeq := time.Now()
-		recsSeq := ml.RecommendItemBased(ds, userID, topK, metric, neighborK)
+		recsSeq, err := model.Recommend(ctx, userID, opts)
 		durSeq := time.Since(startSeq)
+		if err != nil {
+			log.Fatal(err)
+		}
 		fmt.Printf("  Secuencial: %v\n", durSeq)
 
 		fmt.Println("    Ejemplo resultados (Secuencial):")
@@ -76,13 +104,33 @@ func main() {
 			fmt.Printf("    %02d) movie=%d\n", i+1, r.MovieID)
 		}
 
+		// EXPLICACIONES (fuera de la medición de tiempos)
+		if *explain {
+			explained, err := model.Recommend(ctx, userID, ml.RecommendOptions{TopK: topK, Explain: true})
+			if err != nil {
+				log.Fatal(err)
+			}
+			if *asJSON {
+				out, _ := json.MarshalIndent(explained, "    ", "  ")
+				fmt.Printf("    %s\n", out)
+			} else {
+				printExplanations(explained)
+			}
+		}
+
 		// PARALELO con diferentes workers
 		for _, workers := range []int{2, 4, 8, runtime.NumCPU()} {
+			model.Workers = workers
 			startPar := time.Now()
-			recsPar := ml.RecommendItemBasedParallel(ds, userID, topK, metric, neighborK, workers)
+			recsPar, err := model.Recommend(ctx, userID, opts)
 			durPar := time.Since(startPar)
+			if err != nil {
+				log.Fatal(err)
+			}
 			speedup := float64(durSeq) / float64(durPar)
-			fmt.Printf("  Paralelo (%2d workers): %-10v → Speedup: %.2fx\n", workers, durPar, speedup)
+			efficiency := speedup / float64(workers)
+			fmt.Printf("  Paralelo (%2d workers): %-10v → Speedup: %.2fx  Eficiencia: %.2f  Igual a secuencial: %s\n",
+				workers, durPar, speedup, efficiency, yesNo(sameResults(recsSeq, recsPar)))
 
 			fmt.Println("    Ejemplo resultados (Paralelo):")
 			for i := 0; i < 3 && i < len(recsPar); i++ {
@@ -92,6 +140,82 @@ func main() {
 		}
 		fmt.Println()
 	}
+
+	//---------------------------------------------
+	// EXPLORACIÓN (películas nuevas en la lista)
+	//---------------------------------------------
+	if *exploreFlag != "" {
+		param := 0.1 // ε de epsilon
+		if *exploreFlag == "ucb1" {
+			param = 1 // C de ucb1
+		}
+		policy, err := bandit.ParsePolicy(*exploreFlag, param)
+		if err != nil {
+			log.Fatal(err)
+		}
+		banner("Exploración: " + policy.Name())
+		ex := bandit.NewExplorer(policy, time.Now().UnixNano())
+		// sin movies.csv no hay de dónde sacar películas nuevas
+		if cat, err := ml.LoadCatalog(filepath.Join(filepath.Dir(datasetPath), "movies.csv"), ""); err == nil {
+			ex.Catalog = cat
+		}
+		rec := ml.WithReranker(ml.NewItemKNN(ml.CosineSim, neighborK), ex)
+		if err := rec.Fit(ds); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Películas nuevas (sin ratings): %d\n", len(ex.Cold()))
+		recs, err := rec.Recommend(ctx, userID, ml.RecommendOptions{TopK: topK})
+		if err != nil {
+			log.Fatal(err)
+		}
+		cold := make(map[int]bool, len(ex.Cold()))
+		for _, id := range ex.Cold() {
+			cold[id] = true
+		}
+		for i, r := range recs {
+			mark := ""
+			if cold[r.MovieID] {
+				mark = "  (nueva)"
+			}
+			fmt.Printf("  %02d) movie=%d score=%.4f%s\n", i+1, r.MovieID, r.Score, mark)
+		}
+	}
+}
+
+// printExplanations: cada recomendación con los vecinos que más aportaron
+func printExplanations(recs []ml.ItemScore) {
+	fmt.Println("    Explicaciones:")
+	for i, r := range recs {
+		fmt.Printf("    %02d) movie=%d score=%.4f\n", i+1, r.MovieID, r.Score)
+		if r.Why == nil {
+			continue
+		}
+		fmt.Printf("        métrica=%s vecinos=%d (k=%d)\n", r.Why.Metric, r.Why.Neighbors, r.Why.NeighborK)
+		for _, c := range r.Why.Contributions {
+			fmt.Printf("        - %s=%d sim=%.3f rating=%.1f peso=%.1f%%\n",
+				r.Why.Kind, c.ID, c.Similarity, c.Rating*5, c.Weight*100)
+		}
+	}
+}
+
+// sameResults: misma lista (ids y scores) en el mismo orden
+func sameResults(a, b []ml.ItemScore) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "sí"
+	}
+	return "no"
 }
 
 // convertir enum a texto
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"TF/internal/cli"
+	"TF/internal/eval"
+	"TF/internal/ml"
+	"TF/internal/sim"
+)
+
+func main() {
+	models := flag.String("models", "item-cosine,user-cosine,popularity", "modelos a simular, separados por comas (item-cosine, item-pearson, item-jaccard, user-cosine, user-pearson, user-jaccard, slopeone, popularity)")
+	steps := flag.Int("steps", 12, "pasos de simulación")
+	stepDays := flag.Int("step-days", 30, "días simulados por paso")
+	topN := flag.Int("topn", 10, "largo de la lista mostrada")
+	active := flag.Float64("active", 0.3, "fracción de usuarios activos en cada paso")
+	threshold := flag.Float64("threshold", 3.5, "rating oculto (estrellas) con 50% de aceptación")
+	temperature := flag.Float64("temperature", 0.5, "qué tan gradual es la aceptación alrededor de -threshold (estrellas)")
+	noise := flag.Float64("noise", 0.5, "ruido de las preferencias ocultas (estrellas)")
+	neighborK := flag.Int("k", 30, "vecinos de los modelos KNN")
+	testFraction := flag.Float64("test", 0.2, "fracción de ratings reales apartada para RMSE/MAE y ranking (0 = no evaluar)")
+	seed := flag.Int64("seed", 42, "semilla")
+	workers := flag.Int("workers", 0, "goroutines (0 = NumCPU)")
+	format := flag.String("format", "text", "formato de salida: text, csv o json")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Uso: go run ./cmd/simulate [-models item-cosine,user-cosine,popularity] [-steps 12] [-step-days 30] [-topn 10] [-active 0.3] [-threshold 3.5] [-temperature 0.5] [-noise 0.5] [-test 0.2] [-seed 42] [-format text|csv|json] [10|20|25]")
+		return
+	}
+	datasetPath, err := cli.DatasetPath(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var recs []ml.Recommender
+	for _, name := range strings.Split(*models, ",") {
+		rec, err := cli.ModelByName(strings.TrimSpace(name), *neighborK)
+		if err != nil {
+			log.Fatalf("-models: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ds, err := ml.LoadDataset(datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// sin movies.csv las preferencias ocultas no tienen afinidad de género
+	cat, err := ml.LoadCatalog(filepath.Join(filepath.Dir(datasetPath), "movies.csv"), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "catálogo: %v (sin afinidad de género)\n", err)
+		cat = nil
+	}
+	start, test := ds, (*ml.Dataset)(nil)
+	if *testFraction > 0 {
+		start, test = ds.Split(*testFraction, *seed)
+	}
+	prefs := sim.NewHiddenPreferences(ds, cat, ml.NormalizeRating(*noise), *seed)
+	fmt.Fprintf(os.Stderr, "Dataset %s: %d ratings iniciales, %d pasos de %d días\n", datasetPath, start.NumRatings(), *steps, *stepDays)
+
+	opts := sim.Options{
+		Steps:          *steps,
+		StepDuration:   time.Duration(*stepDays) * 24 * time.Hour,
+		K:              *topN,
+		ActiveFraction: *active,
+		Threshold:      ml.NormalizeRating(*threshold),
+		Temperature:    ml.NormalizeRating(*temperature),
+		Test:           test,
+		Seed:           *seed,
+		Workers:        *workers,
+	}
+	var results []sim.Result
+	for _, rec := range recs {
+		fmt.Fprintf(os.Stderr, "Simulando %s...\n", rec.Name())
+		res, err := sim.Run(ctx, rec, start, prefs, opts)
+		if err != nil {
+			log.Fatalf("%s: %v", rec.Name(), err)
+		}
+		results = append(results, res)
+	}
+
+	switch *format {
+	case "json":
+		err = eval.WriteJSON(os.Stdout, results)
+	case "csv":
+		err = eval.WriteCSV(os.Stdout, sim.Table(results))
+	default:
+		err = eval.WriteText(os.Stdout, sim.Table(results))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"TF/internal/cli"
+	"TF/internal/eval"
+	"TF/internal/ml"
+)
+
+// params: columnas de la grilla, en el orden en que se reportan
+var params = []string{"model", "metric", "neighbors", "minsupport", "shrinkage"}
+
+func main() {
+	models := flag.String("model", "item,user", "modelos KNN a probar: item, user")
+	metrics := flag.String("metric", "cosine,pearson,jaccard", "similitudes a probar")
+	neighbors := flag.String("neighbors", "10,30,50", "valores de neighborK")
+	minSupport := flag.String("minsupport", "0,3", "valores de co-calificaciones mínimas")
+	shrinkage := flag.String("shrinkage", "0,10", "valores de shrinkage")
+	folds := flag.Int("folds", 5, "folds de la validación cruzada")
+	seed := flag.Int64("seed", 42, "semilla de los folds")
+	parallel := flag.Int("parallel", 0, "evaluaciones (config, fold) simultáneas (0 = NumCPU)")
+	ranking := flag.Bool("ranking", true, "evaluar también métricas top-N")
+	cutoffs := flag.String("cutoffs", "10", "cortes k de las métricas de ranking")
+	threshold := flag.Float64("threshold", 4, "rating de test (estrellas) para considerar relevante un item")
+	optimize := flag.String("optimize", "rmse", "métrica a optimizar (rmse, mae, ndcg@10, ...)")
+	out := flag.String("out", "", "archivo de resultados (.csv o .json)")
+	correction := flag.String("correction", "holm", "corrección al comparar la mejor contra el resto: none, holm o bonferroni")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Uso: go run ./cmd/tune [-model item,user] [-metric cosine,pearson] [-neighbors 10,30,50] [-minsupport 0,3] [-shrinkage 0,10] [-folds 5] [-optimize rmse] [-correction holm] [-out resultados.csv] [10|20|25]")
+		return
+	}
+	corr, err := eval.ParseCorrection(*correction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	datasetPath, err := cli.DatasetPath(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ks, err := cli.ParseInts(*cutoffs)
+	if err != nil {
+		log.Fatalf("-cutoffs: %v", err)
+	}
+	if *out != "" {
+		if err := checkOut(*out); err != nil {
+			log.Fatalf("-out: %v", err)
+		}
+	}
+	if *folds < 2 {
+		log.Fatalf("-folds: se necesitan al menos 2 (hay %d)", *folds)
+	}
+	configs, err := grid(*models, *metrics, *neighbors, *minSupport, *shrinkage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ds, err := ml.LoadDataset(datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Dataset %s: %d ratings, %d configuraciones x %d folds\n", datasetPath, ds.NumRatings(), len(configs), *folds)
+
+	kfolds, err := ds.KFold(*folds, *seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	results, err := eval.CrossValidate(ctx, configs, kfolds, eval.CVOptions{
+		Ranking:        *ranking,
+		RankingOptions: eval.RankingOptions{Cutoffs: ks, Threshold: ml.NormalizeRating(*threshold)},
+		Parallel:       *parallel,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	table := eval.CVTable(results, params)
+	if err := eval.WriteText(os.Stdout, table); err != nil {
+		log.Fatal(err)
+	}
+
+	best, err := eval.Best(results, *optimize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, _ := best.Metric(*optimize)
+	fmt.Printf("\nMejor configuración según %s: %s (%.4f ± %.4f)\n", *optimize, best.Config, m.Mean, m.Std)
+
+	// ¿la ganadora es realmente mejor que cada una de las demás, o es ruido?
+	var names []string
+	var others []eval.PerUser
+	for _, r := range results {
+		if r.Config != best.Config {
+			names = append(names, r.Config)
+			others = append(others, r.PerUser)
+		}
+	}
+	cmps := eval.CompareAll([]string{*optimize}, best.Config, best.PerUser, names, others, eval.CompareOptions{Seed: *seed, Correction: corr})
+	fmt.Println()
+	if err := eval.WriteText(os.Stdout, eval.SignificanceTable(cmps, corr)); err != nil {
+		log.Fatal(err)
+	}
+
+	if *out != "" {
+		if err := writeResults(*out, table, tuneResults{Results: results, Best: best.Config, Significance: cmps}); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Resultados guardados en %s\n", *out)
+	}
+}
+
+// grid: producto cartesiano de los valores de cada parámetro
+func grid(models, metrics, neighbors, minSupport, shrinkage string) ([]eval.Config, error) {
+	ks, err := cli.ParseInts(neighbors)
+	if err != nil {
+		return nil, fmt.Errorf("-neighbors: %w", err)
+	}
+	sups, err := cli.ParseInts(minSupport)
+	if err != nil {
+		return nil, fmt.Errorf("-minsupport: %w", err)
+	}
+	var shrinks []float64
+	for _, f := range strings.Split(shrinkage, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("-shrinkage: %w", err)
+		}
+		shrinks = append(shrinks, x)
+	}
+
+	var configs []eval.Config
+	for _, model := range strings.Split(models, ",") {
+		model = strings.TrimSpace(model)
+		if model != "item" && model != "user" {
+			return nil, fmt.Errorf("modelo desconocido %q (usa item o user)", model)
+		}
+		for _, name := range strings.Split(metrics, ",") {
+			metric, err := metricByName(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range ks {
+				for _, sup := range sups {
+					for _, shrink := range shrinks {
+						newModel := newKNN(model, metric, k, sup, shrink)
+						configs = append(configs, eval.Config{
+							Name: newModel().Name(),
+							Params: map[string]string{
+								"model":      model,
+								"metric":     metric.String(),
+								"neighbors":  strconv.Itoa(k),
+								"minsupport": strconv.Itoa(sup),
+								"shrinkage":  strconv.FormatFloat(shrink, 'g', -1, 64),
+							},
+							New: newModel,
+						})
+					}
+				}
+			}
+		}
+	}
+	return configs, nil
+}
+
+// newKNN: constructor de un modelo de la grilla
+func newKNN(model string, metric ml.SimMetric, k, minSupport int, shrinkage float64) func() ml.Recommender {
+	return func() ml.Recommender {
+		if model == "user" {
+			m := ml.NewUserKNN(metric, k)
+			m.MinSupport = minSupport
+			m.Shrinkage = shrinkage
+			return m
+		}
+		m := ml.NewItemKNN(metric, k)
+		m.MinSupport = minSupport
+		m.Shrinkage = shrinkage
+		return m
+	}
+}
+
+func metricByName(name string) (ml.SimMetric, error) {
+	for _, m := range []ml.SimMetric{ml.CosineSim, ml.PearsonSim, ml.JaccardSim} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("similitud desconocida %q (usa cosine, pearson o jaccard)", name)
+}
+
+// tuneResults: contenido del JSON de salida
+type tuneResults struct {
+	Results      []eval.CVResult   `json:"results"`
+	Best         string            `json:"best"`
+	Significance []eval.Comparison `json:"significance"`
+}
+
+// writeResults: CSV (la tabla de la grilla) o JSON (todo) según la extensión del archivo
+func writeResults(path string, table eval.Table, results tuneResults) error {
+	if err := checkOut(path); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = eval.WriteJSON(f, results)
+	} else {
+		err = eval.WriteCSV(f, table)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// checkOut: la extensión de -out tiene que ser .csv o .json
+func checkOut(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".json":
+		return nil
+	default:
+		return fmt.Errorf("extensión no soportada %q (usa .csv o .json)", filepath.Ext(path))
+	}
+}
@@ -0,0 +1,243 @@
+package bandit
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"TF/internal/experiment"
+	"TF/internal/ml"
+)
+
+// ----------------- Capa de exploración -----------------
+
+// DefaultPriorWeight: cuántas exposiciones "vale" la relevancia del recomendador
+const DefaultPriorWeight = 2
+
+// Explorer: re-ranker que llena la lista lugar por lugar con una Policy sobre
+// los candidatos del recomendador más algunas películas nuevas (sin ratings en
+// el dataset de Fit), que de otro modo nunca se mostrarían. Se usa con
+// ml.WithReranker; Reranked.Fit llama a Fit.
+type Explorer struct {
+	Policy      Policy
+	State       *State      // recompensas por película (nil -> NewState())
+	PriorWeight float64     // peso de la relevancia como prior (0 -> DefaultPriorWeight)
+	Catalog     *ml.Catalog // de dónde salen las películas nuevas (nil -> no se agregan)
+	ColdRatings int         // películas con hasta esta cantidad de ratings cuentan como nuevas
+	MaxCold     int         // nuevas agregadas a cada lista de candidatos (0 -> k)
+
+	mu   sync.Mutex
+	rng  *rand.Rand
+	cold []int
+}
+
+// NewExplorer crea la capa de exploración con su propio estado
+func NewExplorer(p Policy, seed int64) *Explorer {
+	return &Explorer{Policy: p, State: NewState(), rng: rand.New(rand.NewSource(seed))}
+}
+
+func (e *Explorer) Name() string {
+	return "explore(" + e.Policy.Name() + ")"
+}
+
+// Fit arma la lista de películas nuevas: las del catálogo con hasta ColdRatings ratings en ds
+func (e *Explorer) Fit(ds *ml.Dataset) error {
+	if e.State == nil {
+		e.State = NewState()
+	}
+	if e.rng == nil {
+		e.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	e.cold = nil
+	if e.Catalog == nil {
+		return nil
+	}
+	counts := make(map[int]int)
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			counts[it]++
+		}
+	}
+	for id := range e.Catalog.Movies {
+		if counts[id] <= e.ColdRatings {
+			e.cold = append(e.cold, id)
+		}
+	}
+	sort.Ints(e.cold)
+	return nil
+}
+
+// Cold: películas nuevas detectadas en Fit
+func (e *Explorer) Cold() []int {
+	return e.cold
+}
+
+func (e *Explorer) priorWeight() float64 {
+	if e.PriorWeight <= 0 {
+		return DefaultPriorWeight
+	}
+	return e.PriorWeight
+}
+
+func (e *Explorer) Rerank(user int, items []ml.ItemScore, k int) []ml.ItemScore {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cands := append([]ml.ItemScore(nil), items...)
+	rel := normalize(items)
+	if len(e.cold) > 0 {
+		n := e.MaxCold
+		if n <= 0 {
+			n = k
+		}
+		in := make(map[int]bool, len(items))
+		for _, it := range items {
+			in[it.MovieID] = true
+		}
+		// muestra al azar de las nuevas (sin las que ya trajo el recomendador)
+		for _, i := range e.rng.Perm(len(e.cold)) {
+			if n == 0 {
+				break
+			}
+			if id := e.cold[i]; !in[id] {
+				cands = append(cands, ml.ItemScore{MovieID: id})
+				n--
+			}
+		}
+	}
+
+	w := e.priorWeight()
+	total := e.State.Total()
+	arms := make([]Arm, len(cands))
+	for i, c := range cands {
+		st := e.State.Get(c.MovieID)
+		st.Reward = max(0, min(st.Reward, st.Pulls)) // Update acepta cualquier valor
+		r := rel[c.MovieID]
+		arms[i] = Arm{
+			MovieID:   c.MovieID,
+			Relevance: r,
+			Pulls:     st.Pulls,
+			Alpha:     1 + st.Reward + w*r,
+			Beta:      1 + (st.Pulls - st.Reward) + w*(1-r),
+		}
+	}
+
+	k = min(k, len(cands))
+	out := make([]ml.ItemScore, 0, k)
+	for len(out) < k {
+		i := e.Policy.Select(arms, total, e.rng)
+		out = append(out, cands[i])
+		cands = append(cands[:i], cands[i+1:]...)
+		arms = append(arms[:i], arms[i+1:]...)
+	}
+	return out
+}
+
+// normalize: scores a [0,1] (min-max) por película
+func normalize(items []ml.ItemScore) map[int]float64 {
+	out := make(map[int]float64, len(items))
+	if len(items) == 0 {
+		return out
+	}
+	lo, hi := items[0].Score, items[0].Score
+	for _, it := range items {
+		lo = min(lo, it.Score)
+		hi = max(hi, it.Score)
+	}
+	for _, it := range items {
+		if hi == lo {
+			out[it.MovieID] = 1
+		} else {
+			out[it.MovieID] = (it.Score - lo) / (hi - lo)
+		}
+	}
+	return out
+}
+
+// ----------------- Evaluación por replay -----------------
+
+// ReplayResult: CTR estimado de una política sobre un log
+type ReplayResult struct {
+	Policy      string  `json:"policy"`
+	Impressions int     `json:"impressions"` // impresiones del log
+	Slots       int     `json:"slots"`       // películas mostradas en el log
+	Matched     int     `json:"matched"`     // de ellas, las que la política también habría mostrado
+	Clicks      int     `json:"clicks"`      // clicks sobre las coincidentes
+	CTR         float64 `json:"ctr"`         // Clicks / Matched: CTR estimado de la política
+	LoggedCTR   float64 `json:"logged_ctr"`  // CTR por película del log completo
+	NewShown    int     `json:"new_shown"`   // lugares de la política ocupados por películas nuevas
+}
+
+// Replay evalúa offline la capa de exploración (Li et al., 2011). Recorre las
+// impresiones del log en orden temporal; para cada una pide a rec (envuelto con
+// ex) una lista de k películas y sólo cuenta las películas mostradas en el log
+// que la política también habría mostrado, con su click real como recompensa,
+// que además actualiza ex.State. El estimador es insesgado si el log se generó
+// con una política aleatoria; con logs de otro recomendador favorece a las
+// políticas parecidas a ese recomendador. rec ya debe estar entrenado.
+func Replay(ctx context.Context, rec ml.Recommender, ex *Explorer, events []experiment.Event, k int) (ReplayResult, error) {
+	res := ReplayResult{Policy: ex.Policy.Name()}
+	clicked := make(map[string]map[int]bool)
+	var imps []experiment.Event
+	for _, ev := range events {
+		switch ev.Type {
+		case experiment.ImpressionEvent:
+			imps = append(imps, ev)
+		case experiment.ClickEvent:
+			if clicked[ev.Impression] == nil {
+				clicked[ev.Impression] = make(map[int]bool)
+			}
+			clicked[ev.Impression][ev.Item] = true
+		}
+	}
+	sort.SliceStable(imps, func(i, j int) bool { return imps[i].Time.Before(imps[j].Time) })
+
+	cold := make(map[int]bool, len(ex.cold))
+	for _, id := range ex.cold {
+		cold[id] = true
+	}
+	loggedClicks := 0
+	for _, imp := range imps {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		recs, err := rec.Recommend(ctx, imp.User, ml.RecommendOptions{TopK: k})
+		if err != nil {
+			return res, err
+		}
+		shown := make(map[int]bool, len(recs))
+		for _, it := range recs {
+			shown[it.MovieID] = true
+			if cold[it.MovieID] {
+				res.NewShown++
+			}
+		}
+		res.Impressions++
+		for _, it := range imp.Items {
+			res.Slots++
+			click := clicked[imp.Impression][it]
+			if click {
+				loggedClicks++
+			}
+			if !shown[it] {
+				continue
+			}
+			res.Matched++
+			reward := 0.0
+			if click {
+				res.Clicks++
+				reward = 1
+			}
+			ex.State.Update(it, 1, reward)
+		}
+	}
+	if res.Matched > 0 {
+		res.CTR = float64(res.Clicks) / float64(res.Matched)
+	}
+	if res.Slots > 0 {
+		res.LoggedCTR = float64(loggedClicks) / float64(res.Slots)
+	}
+	return res, nil
+}
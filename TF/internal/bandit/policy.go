@@ -0,0 +1,146 @@
+package bandit
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ----------------- Políticas -----------------
+
+// Arm: una película candidata para un lugar de la lista. La media combina lo
+// observado con la relevancia del recomendador como prior: Beta(Alpha, Beta) con
+//
+//	Alpha = 1 + recompensa + w*relevancia
+//	Beta  = 1 + (exposiciones - recompensa) + w*(1-relevancia)
+type Arm struct {
+	MovieID     int
+	Relevance   float64 // score del recomendador normalizado a [0,1] (0 para las nuevas)
+	Pulls       float64 // exposiciones observadas
+	Alpha, Beta float64
+}
+
+// Mean: media de la Beta
+func (a Arm) Mean() float64 {
+	return a.Alpha / (a.Alpha + a.Beta)
+}
+
+// Policy elige cuál de los candidatos ocupa el próximo lugar de la lista. total
+// son las exposiciones de todas las películas (para UCB1).
+type Policy interface {
+	Name() string
+	Select(arms []Arm, total float64, rng *rand.Rand) int
+}
+
+// argmax: primer índice con el mayor valor (los empates respetan el orden del recomendador)
+func argmax(arms []Arm, value func(Arm) float64) int {
+	best, bestVal := 0, math.Inf(-1)
+	for i, a := range arms {
+		if v := value(a); v > bestVal {
+			best, bestVal = i, v
+		}
+	}
+	return best
+}
+
+// EpsilonGreedy: con probabilidad Epsilon un candidato al azar, si no el de
+// mayor media. Epsilon=0 es la política puramente explotadora.
+type EpsilonGreedy struct {
+	Epsilon float64
+}
+
+func (p EpsilonGreedy) Name() string {
+	return fmt.Sprintf("epsilon-greedy(ε=%.2f)", p.Epsilon)
+}
+
+func (p EpsilonGreedy) Select(arms []Arm, _ float64, rng *rand.Rand) int {
+	if rng.Float64() < p.Epsilon {
+		return rng.Intn(len(arms))
+	}
+	return argmax(arms, Arm.Mean)
+}
+
+// UCB1: media + C*sqrt(2 ln(total+1) / n), con n las exposiciones más el peso
+// del prior. Las películas poco mostradas tienen un bono grande.
+type UCB1 struct {
+	C float64 // 0 -> 1
+}
+
+func (p UCB1) Name() string {
+	return fmt.Sprintf("ucb1(c=%.2f)", p.c())
+}
+
+func (p UCB1) c() float64 {
+	if p.C <= 0 {
+		return 1
+	}
+	return p.C
+}
+
+func (p UCB1) Select(arms []Arm, total float64, _ *rand.Rand) int {
+	lt := math.Log(total + 1)
+	return argmax(arms, func(a Arm) float64 {
+		// Alpha+Beta-2 = exposiciones + peso del prior
+		n := a.Alpha + a.Beta - 2
+		if n <= 0 {
+			return math.Inf(1)
+		}
+		return a.Mean() + p.c()*math.Sqrt(2*lt/n)
+	})
+}
+
+// Thompson: una muestra de la Beta de cada candidato, gana la mayor
+type Thompson struct{}
+
+func (Thompson) Name() string { return "thompson" }
+
+func (Thompson) Select(arms []Arm, _ float64, rng *rand.Rand) int {
+	return argmax(arms, func(a Arm) float64 {
+		return sampleBeta(rng, a.Alpha, a.Beta)
+	})
+}
+
+// ParsePolicy: "greedy", "epsilon", "ucb1" o "thompson"; param es ε para
+// epsilon y C para ucb1
+func ParsePolicy(name string, param float64) (Policy, error) {
+	switch name {
+	case "greedy":
+		return EpsilonGreedy{}, nil
+	case "epsilon":
+		return EpsilonGreedy{Epsilon: param}, nil
+	case "ucb1":
+		return UCB1{C: param}, nil
+	case "thompson":
+		return Thompson{}, nil
+	default:
+		return nil, fmt.Errorf("bandit: política desconocida %q (usa greedy, epsilon, ucb1 o thompson)", name)
+	}
+}
+
+// sampleBeta: X/(X+Y) con X ~ Gamma(a), Y ~ Gamma(b)
+func sampleBeta(rng *rand.Rand, a, b float64) float64 {
+	x := sampleGamma(rng, a)
+	y := sampleGamma(rng, b)
+	return x / (x + y)
+}
+
+// sampleGamma: Marsaglia-Tsang (shape < 1 se lleva a shape+1)
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
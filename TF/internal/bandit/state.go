@@ -0,0 +1,101 @@
+package bandit
+
+import (
+	"sync"
+
+	"TF/internal/experiment"
+)
+
+// ----------------- Estado de recompensas por película -----------------
+
+// Stats: veces que se mostró una película y recompensa acumulada (clicks, o
+// cualquier recompensa en [0,1] por exposición)
+type Stats struct {
+	Pulls  float64 `json:"pulls"`
+	Reward float64 `json:"reward"`
+}
+
+// State: estadísticas de cada película compartidas por todos los usuarios; es
+// seguro para varias goroutines
+type State struct {
+	mu    sync.RWMutex
+	items map[int]Stats
+	total float64
+	open  map[string]map[int]bool // impresión aplicada -> películas todavía sin click
+}
+
+// NewState crea un estado vacío
+func NewState() *State {
+	return &State{items: make(map[int]Stats), open: make(map[string]map[int]bool)}
+}
+
+// Update suma exposiciones y recompensa a una película
+func (s *State) Update(item int, pulls, reward float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(item, pulls, reward)
+}
+
+func (s *State) update(item int, pulls, reward float64) {
+	st := s.items[item]
+	st.Pulls += pulls
+	st.Reward += reward
+	s.items[item] = st
+	s.total += pulls
+}
+
+// Apply incorpora un evento del log de experimentos: una impresión cuenta una
+// exposición para cada película mostrada y un click, recompensa 1 para la suya.
+// Cada (impresión, película) recompensa a lo sumo una vez; se ignoran los clicks
+// repetidos, los de impresiones no aplicadas y los de películas que no se
+// mostraron en ella, así Reward nunca supera a Pulls.
+func (s *State) Apply(ev experiment.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch ev.Type {
+	case experiment.ImpressionEvent:
+		if _, dup := s.open[ev.Impression]; dup {
+			return
+		}
+		shown := make(map[int]bool, len(ev.Items))
+		for _, it := range ev.Items {
+			if shown[it] {
+				continue
+			}
+			shown[it] = true
+			s.update(it, 1, 0)
+		}
+		s.open[ev.Impression] = shown
+	case experiment.ClickEvent:
+		if !s.open[ev.Impression][ev.Item] {
+			return
+		}
+		delete(s.open[ev.Impression], ev.Item)
+		s.update(ev.Item, 0, 1)
+	}
+}
+
+// Get devuelve las estadísticas de una película (cero si nunca se mostró)
+func (s *State) Get(item int) Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items[item]
+}
+
+// Total: exposiciones de todas las películas
+func (s *State) Total() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.total
+}
+
+// Snapshot: copia de las estadísticas (p.ej. para guardarlas en JSON)
+func (s *State) Snapshot() map[int]Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[int]Stats, len(s.items))
+	for it, st := range s.items {
+		out[it] = st
+	}
+	return out
+}
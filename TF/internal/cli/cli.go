@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Utilidades compartidas por los comandos -----------------
+
+// DatasetPath: ruta del ratings.csv según el tamaño (misma convención que cmd/node)
+func DatasetPath(size string) (string, error) {
+	switch size {
+	case "10":
+		return "dataset/10M/ratings.csv", nil
+	case "20":
+		return "dataset/20M/ratings.csv", nil
+	case "25":
+		return "dataset/25M/ratings.csv", nil
+	default:
+		return "", fmt.Errorf("tamaño no válido: %s (usa 10, 20 o 25)", size)
+	}
+}
+
+// ModelByName: recomendador sin entrenar a partir de su nombre corto
+// (item-cosine, user-pearson, slopeone, popularity, ...); k son los vecinos de los KNN
+func ModelByName(name string, k int) (ml.Recommender, error) {
+	switch name {
+	case "item-cosine":
+		return ml.NewItemKNN(ml.CosineSim, k), nil
+	case "item-pearson":
+		return ml.NewItemKNN(ml.PearsonSim, k), nil
+	case "item-jaccard":
+		return ml.NewItemKNN(ml.JaccardSim, k), nil
+	case "user-cosine":
+		return ml.NewUserKNN(ml.CosineSim, k), nil
+	case "user-pearson":
+		return ml.NewUserKNN(ml.PearsonSim, k), nil
+	case "user-jaccard":
+		return ml.NewUserKNN(ml.JaccardSim, k), nil
+	case "slopeone":
+		return ml.NewSlopeOne(ml.WeightedSlopeOne), nil
+	case "popularity":
+		return ml.NewPopularity(), nil
+	default:
+		return nil, fmt.Errorf("modelo desconocido %q", name)
+	}
+}
+
+// ParseInts: lista de enteros separados por comas ("5,10,20")
+func ParseInts(s string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,255 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Métricas más allá de la precisión -----------------
+
+// BeyondOptions: parámetros de EvaluateBeyond
+type BeyondOptions struct {
+	K          int               // largo de cada lista (0 -> 10)
+	SampleSize int               // usuarios de la muestra (0 -> todos los de test)
+	Seed       int64             // semilla de la muestra
+	Sim        ml.ItemSimilarity // similitud para la diversidad intra-lista (nil -> no se calcula)
+	SimName    string            // nombre de Sim para el reporte
+	Baseline   ml.Recommender    // serendipia: lo "esperable" (nil -> Popularity sobre train)
+	Threshold  float64           // serendipia: rating de test relevante (0 -> DefaultThreshold)
+	Workers    int               // goroutines (<= 0 -> runtime.NumCPU())
+}
+
+// BeyondResult: cuán variadas y poco obvias son las listas de un modelo.
+// Todas las medias son sobre los usuarios de la muestra que recibieron alguna recomendación.
+type BeyondResult struct {
+	Model           string        `json:"model"`
+	Users           int           `json:"users"`
+	K               int           `json:"k"`
+	CatalogCoverage float64       `json:"catalog_coverage"`     // items distintos recomendados / items de train
+	Gini            float64       `json:"gini"`                 // desigualdad de exposición (0 = pareja, 1 = un solo item)
+	Novelty         float64       `json:"novelty"`              // autoinformación media -log2(pop(i)/usuarios), en bits
+	MeanPopRank     float64       `json:"mean_popularity_rank"` // rank medio de popularidad (1 = la más calificada)
+	ILD             float64       `json:"ild"`                  // diversidad intra-lista: media de 1-sim entre pares
+	ILDMetric       string        `json:"ild_metric,omitempty"`
+	Serendipity     float64       `json:"serendipity"` // fracción de la lista relevante y ausente del baseline
+	Baseline        string        `json:"baseline"`
+	EvalTime        time.Duration `json:"eval_time_ns"`
+}
+
+// userBeyond: aportes de un usuario
+type userBeyond struct {
+	items                 []int
+	novelty, popRank, ild float64
+	serendipity           float64
+	hasILD                bool
+}
+
+// EvaluateBeyond entrena rec (y el baseline) sobre train, pide K recomendaciones
+// para cada usuario de la muestra y mide cobertura, concentración, novedad,
+// diversidad y serendipia de esas listas
+func EvaluateBeyond(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, opts BeyondOptions) (BeyondResult, error) {
+	k := opts.K
+	if k <= 0 {
+		k = 10
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	baseline := opts.Baseline
+	if baseline == nil {
+		baseline = ml.NewPopularity()
+	}
+	res := BeyondResult{Model: rec.Name(), K: k, ILDMetric: opts.SimName, Baseline: baseline.Name()}
+
+	if err := rec.Fit(train); err != nil {
+		return res, err
+	}
+	if err := baseline.Fit(train); err != nil {
+		return res, err
+	}
+
+	start := time.Now()
+	counts := itemCounts(train)
+	rank := popularityRank(counts)
+	nUsers := float64(len(train.UserRatings))
+
+	users := sampleUsers(test, opts.SampleSize, opts.Seed)
+	perUser := make([]userBeyond, len(users))
+	errs := make([]error, len(users))
+	forEachUser(ctx, users, opts.Workers, func(i, u int) {
+		recs, err := rec.Recommend(ctx, u, ml.RecommendOptions{TopK: k})
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		if len(recs) == 0 {
+			return
+		}
+		expected, err := baseline.Recommend(ctx, u, ml.RecommendOptions{TopK: k})
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		inBaseline := make(map[int]bool, len(expected))
+		for _, it := range expected {
+			inBaseline[it.MovieID] = true
+		}
+
+		ub := userBeyond{items: make([]int, len(recs))}
+		serendipitous := 0
+		for j, it := range recs {
+			ub.items[j] = it.MovieID
+			// +1: los items que nadie calificó en train no dan información infinita
+			ub.novelty += -math.Log2(float64(counts[it.MovieID]+1) / (nUsers + 1))
+			r, ok := rank[it.MovieID]
+			if !ok {
+				r = len(rank) + 1
+			}
+			ub.popRank += float64(r)
+			if rt, ok := test.UserRatings[u][it.MovieID]; ok && rt >= threshold && !inBaseline[it.MovieID] {
+				serendipitous++
+			}
+		}
+		n := float64(len(recs))
+		ub.novelty /= n
+		ub.popRank /= n
+		ub.serendipity = float64(serendipitous) / n
+		if opts.Sim != nil && len(recs) > 1 {
+			ub.ild, ub.hasILD = intraListDiversity(ub.items, opts.Sim), true
+		}
+		perUser[i] = ub
+	})
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return res, err
+		}
+	}
+
+	exposure := make(map[int]int)
+	ildUsers := 0
+	for _, ub := range perUser {
+		if len(ub.items) == 0 {
+			continue
+		}
+		res.Users++
+		for _, it := range ub.items {
+			exposure[it]++
+		}
+		res.Novelty += ub.novelty
+		res.MeanPopRank += ub.popRank
+		res.Serendipity += ub.serendipity
+		if ub.hasILD {
+			res.ILD += ub.ild
+			ildUsers++
+		}
+	}
+	if res.Users > 0 {
+		n := float64(res.Users)
+		res.Novelty /= n
+		res.MeanPopRank /= n
+		res.Serendipity /= n
+	}
+	if ildUsers > 0 {
+		res.ILD /= float64(ildUsers)
+	}
+	if len(counts) > 0 {
+		res.CatalogCoverage = float64(len(exposure)) / float64(len(counts))
+	}
+	res.Gini = Gini(exposure, counts)
+	res.EvalTime = time.Since(start)
+	return res, nil
+}
+
+// intraListDiversity: media de 1-sim(a,b) sobre todos los pares de la lista
+func intraListDiversity(items []int, sim ml.ItemSimilarity) float64 {
+	sum, pairs := 0.0, 0
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			sum += 1 - sim(items[i], items[j])
+			pairs++
+		}
+	}
+	return sum / float64(pairs)
+}
+
+// Gini: coeficiente de Gini de la exposición sobre todo el catálogo
+// (los items nunca recomendados cuentan con exposición 0)
+func Gini(exposure map[int]int, catalog map[int]int) float64 {
+	xs := make([]float64, 0, len(catalog))
+	total := 0.0
+	for it := range catalog {
+		x := float64(exposure[it])
+		xs = append(xs, x)
+		total += x
+	}
+	// items recomendados que no están en train (p.ej. content-based)
+	for it, e := range exposure {
+		if _, ok := catalog[it]; !ok {
+			xs = append(xs, float64(e))
+			total += float64(e)
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	sort.Float64s(xs)
+	n := float64(len(xs))
+	sum := 0.0
+	for i, x := range xs {
+		sum += (2*float64(i+1) - n - 1) * x
+	}
+	return sum / (n * total)
+}
+
+// itemCounts: item -> cuántos usuarios lo calificaron
+func itemCounts(ds *ml.Dataset) map[int]int {
+	counts := make(map[int]int)
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			counts[it]++
+		}
+	}
+	return counts
+}
+
+// popularityRank: item -> posición por popularidad (1 = más calificado, desempate
+// por id)
+func popularityRank(counts map[int]int) map[int]int {
+	items := make([]int, 0, len(counts))
+	for it := range counts {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if counts[items[i]] != counts[items[j]] {
+			return counts[items[i]] > counts[items[j]]
+		}
+		return items[i] < items[j]
+	})
+	rank := make(map[int]int, len(items))
+	for i, it := range items {
+		rank[it] = i + 1
+	}
+	return rank
+}
+
+// sampleUsers: n usuarios de ds al azar con semilla (n <= 0 o n >= total -> todos), ordenados
+func sampleUsers(ds *ml.Dataset, n int, seed int64) []int {
+	users := sortedUsers(ds)
+	if n <= 0 || n >= len(users) {
+		return users
+	}
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(users), func(i, j int) { users[i], users[j] = users[j], users[i] })
+	users = users[:n]
+	sort.Ints(users)
+	return users
+}
@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"context"
+	"time"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Calibración -----------------
+
+// CalibrationOptions: parámetros de EvaluateCalibration
+type CalibrationOptions struct {
+	K          int         // largo de cada lista (0 -> 10)
+	SampleSize int         // usuarios de la muestra (0 -> todos los de test)
+	Seed       int64       // semilla de la muestra
+	Catalog    *ml.Catalog // géneros de las películas
+	Alpha      float64     // suavizado de la KL (0 -> ml.DefaultCalibrationAlpha)
+	Workers    int         // goroutines (<= 0 -> runtime.NumCPU())
+}
+
+// CalibrationResult: qué tanto respetan las listas la mezcla de géneros de cada usuario
+type CalibrationResult struct {
+	Model          string        `json:"model"`
+	Users          int           `json:"users"` // usuarios con historia y lista con géneros
+	K              int           `json:"k"`
+	Miscalibration float64       `json:"miscalibration"` // media de KL(p(g|u) || q~(g|lista)); 0 = calibrado
+	GenreRecall    float64       `json:"genre_recall"`   // fracción media de los géneros del usuario presentes en la lista
+	EvalTime       time.Duration `json:"eval_time_ns"`
+	PerUser        PerUser       `json:"-"` // "miscalibration" por usuario
+}
+
+// EvaluateCalibration entrena rec sobre train, pide K recomendaciones para cada
+// usuario de la muestra y compara la distribución de géneros de la lista con la
+// de lo que el usuario calificó en train (Steck, 2018)
+func EvaluateCalibration(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, opts CalibrationOptions) (CalibrationResult, error) {
+	k := opts.K
+	if k <= 0 {
+		k = 10
+	}
+	alpha := opts.Alpha
+	if alpha <= 0 {
+		alpha = ml.DefaultCalibrationAlpha
+	}
+	res := CalibrationResult{Model: rec.Name(), K: k, PerUser: make(PerUser)}
+	if err := rec.Fit(train); err != nil {
+		return res, err
+	}
+
+	start := time.Now()
+	users := sampleUsers(test, opts.SampleSize, opts.Seed)
+	kl := make([]float64, len(users))
+	recall := make([]float64, len(users))
+	ok := make([]bool, len(users))
+	errs := make([]error, len(users))
+	forEachUser(ctx, users, opts.Workers, func(i, u int) {
+		hist := make(map[int]float64, len(train.UserRatings[u]))
+		for it := range train.UserRatings[u] {
+			hist[it] = 1
+		}
+		p := ml.GenreDistribution(opts.Catalog, hist)
+		if len(p) == 0 {
+			return
+		}
+		recs, err := rec.Recommend(ctx, u, ml.RecommendOptions{TopK: k})
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		list := make(map[int]float64, len(recs))
+		for _, it := range recs {
+			list[it.MovieID] = 1
+		}
+		q := ml.GenreDistribution(opts.Catalog, list)
+		if len(q) == 0 {
+			return
+		}
+		kl[i] = ml.Miscalibration(p, q, alpha)
+		covered := 0
+		for g := range p {
+			if q[g] > 0 {
+				covered++
+			}
+		}
+		recall[i] = float64(covered) / float64(len(p))
+		ok[i] = true
+	})
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return res, err
+		}
+	}
+
+	for i, u := range users {
+		if !ok[i] {
+			continue
+		}
+		res.Users++
+		res.Miscalibration += kl[i]
+		res.GenreRecall += recall[i]
+		res.PerUser.add("miscalibration", u, kl[i])
+	}
+	if res.Users > 0 {
+		res.Miscalibration /= float64(res.Users)
+		res.GenreRecall /= float64(res.Users)
+	}
+	res.EvalTime = time.Since(start)
+	return res, nil
+}
@@ -0,0 +1,265 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Validación cruzada y búsqueda de hiperparámetros -----------------
+
+// Config: una combinación de hiperparámetros. New debe devolver un modelo nuevo
+// sin entrenar en cada llamada (los folds se evalúan en paralelo).
+type Config struct {
+	Name   string
+	Params map[string]string
+	New    func() ml.Recommender
+}
+
+// CVOptions: parámetros de CrossValidate
+type CVOptions struct {
+	Ranking        bool           // además de rating, evaluar métricas top-N
+	RankingOptions RankingOptions // cortes, umbral y protocolo del ranking
+	Parallel       int            // evaluaciones (config, fold) simultáneas (<= 0 -> runtime.NumCPU())
+}
+
+// MetricSummary: una métrica a lo largo de los folds
+type MetricSummary struct {
+	Name  string    `json:"name"`
+	Mean  float64   `json:"mean"`
+	Std   float64   `json:"std"` // desvío estándar muestral entre folds
+	Folds []float64 `json:"folds"`
+}
+
+// CVResult: resultado de una configuración
+type CVResult struct {
+	Config  string            `json:"config"`
+	Params  map[string]string `json:"params"`
+	Metrics []MetricSummary   `json:"metrics"`
+	PerUser PerUser           `json:"-"` // media por usuario de los folds donde fue evaluado
+}
+
+// Metric busca una métrica por nombre
+func (r CVResult) Metric(name string) (MetricSummary, bool) {
+	for _, m := range r.Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return MetricSummary{}, false
+}
+
+// LowerIsBetter: métricas de error, donde gana el menor valor
+func LowerIsBetter(metric string) bool {
+	return metric == "rmse" || metric == "mae"
+}
+
+// CrossValidate evalúa cada configuración en cada fold. Todas las combinaciones
+// (config, fold) se reparten entre Parallel goroutines; cada una entrena el modelo
+// una sola vez y corre secuencial por dentro para no sobre-suscribir la CPU. Los
+// resultados quedan en el orden de configs.
+func CrossValidate(ctx context.Context, configs []Config, folds []ml.Fold, opts CVOptions) ([]CVResult, error) {
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+	rankOpts := opts.RankingOptions
+	rankOpts.Workers = 1
+
+	// values[c][f]: métricas (en orden) de la config c en el fold f
+	type job struct{ c, f int }
+	values := make([][][]namedValue, len(configs))
+	perUser := make([][][]PerUser, len(configs))
+	for c := range values {
+		values[c] = make([][]namedValue, len(folds))
+		perUser[c] = make([][]PerUser, len(folds))
+	}
+	errs := make([]error, len(configs)*len(folds))
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				fold := folds[j.f]
+				rec := configs[j.c].New()
+				if err := rec.Fit(fold.Train); err != nil {
+					errs[j.c*len(folds)+j.f] = fmt.Errorf("%s fold %d: %w", configs[j.c].Name, j.f, err)
+					continue
+				}
+				rating, err := ScoreRating(ctx, rec, fold.Test, 1)
+				if err != nil {
+					errs[j.c*len(folds)+j.f] = fmt.Errorf("%s fold %d: %w", configs[j.c].Name, j.f, err)
+					continue
+				}
+				vals := ratingValues(rating)
+				pu := []PerUser{rating.PerUser}
+				if opts.Ranking {
+					ranking, err := ScoreRanking(ctx, rec, fold.Train, fold.Test, rankOpts)
+					if err != nil {
+						errs[j.c*len(folds)+j.f] = fmt.Errorf("%s fold %d: %w", configs[j.c].Name, j.f, err)
+						continue
+					}
+					vals = append(vals, rankingValues(ranking)...)
+					pu = append(pu, ranking.PerUser)
+				}
+				values[j.c][j.f] = vals
+				perUser[j.c][j.f] = pu
+			}
+		}()
+	}
+	for c := range configs {
+		for f := range folds {
+			if ctx.Err() != nil {
+				break
+			}
+			jobs <- job{c, f}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]CVResult, len(configs))
+	for c, cfg := range configs {
+		results[c] = CVResult{Config: cfg.Name, Params: cfg.Params, PerUser: meanPerUser(perUser[c])}
+		if len(folds) == 0 {
+			continue
+		}
+		for m, nv := range values[c][0] {
+			s := MetricSummary{Name: nv.name, Folds: make([]float64, len(folds))}
+			for f := range folds {
+				s.Folds[f] = values[c][f][m].value
+			}
+			s.Mean, s.Std = meanStd(s.Folds)
+			results[c].Metrics = append(results[c].Metrics, s)
+		}
+	}
+	return results, nil
+}
+
+// meanPerUser: promedia, para cada métrica y usuario, los valores de los folds
+// donde el usuario fue evaluado
+func meanPerUser(folds [][]PerUser) PerUser {
+	sums := make(PerUser)
+	counts := make(map[string]map[int]int)
+	for _, parts := range folds {
+		for _, pu := range parts {
+			for metric, byUser := range pu {
+				if counts[metric] == nil {
+					counts[metric] = make(map[int]int)
+				}
+				for u, v := range byUser {
+					sums.add(metric, u, sums[metric][u]+v)
+					counts[metric][u]++
+				}
+			}
+		}
+	}
+	for metric, byUser := range sums {
+		for u := range byUser {
+			byUser[u] /= float64(counts[metric][u])
+		}
+	}
+	return sums
+}
+
+// Best: la configuración con mejor media en metric. En las métricas de error se
+// saltean las configuraciones que en algún fold no predijeron ningún par: su
+// RMSE/MAE de ese fold vale 0 y ganarían sin haber predicho nada.
+func Best(results []CVResult, metric string) (CVResult, error) {
+	best, found := CVResult{}, false
+	bestMean, known := 0.0, false
+	for _, r := range results {
+		m, ok := r.Metric(metric)
+		if !ok {
+			continue
+		}
+		known = true
+		if LowerIsBetter(metric) && !covered(r) {
+			continue
+		}
+		better := m.Mean > bestMean
+		if LowerIsBetter(metric) {
+			better = m.Mean < bestMean
+		}
+		if !found || better {
+			best, bestMean, found = r, m.Mean, true
+		}
+	}
+	if !known {
+		return CVResult{}, fmt.Errorf("eval: métrica desconocida %q", metric)
+	}
+	if !found {
+		return CVResult{}, fmt.Errorf("eval: ninguna configuración predijo pares en todos los folds para %q", metric)
+	}
+	return best, nil
+}
+
+// covered: la configuración predijo al menos un par en cada fold
+func covered(r CVResult) bool {
+	c, ok := r.Metric("coverage")
+	if !ok {
+		return true
+	}
+	for _, v := range c.Folds {
+		if v == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type namedValue struct {
+	name  string
+	value float64
+}
+
+func ratingValues(r RatingResult) []namedValue {
+	return []namedValue{{"rmse", r.RMSE}, {"mae", r.MAE}, {"coverage", r.Coverage}}
+}
+
+func rankingValues(r RankingResult) []namedValue {
+	var out []namedValue
+	for _, m := range r.AtK {
+		out = append(out,
+			namedValue{fmt.Sprintf("precision@%d", m.K), m.Precision},
+			namedValue{fmt.Sprintf("recall@%d", m.K), m.Recall},
+			namedValue{fmt.Sprintf("ndcg@%d", m.K), m.NDCG},
+			namedValue{fmt.Sprintf("hit_rate@%d", m.K), m.HitRate},
+		)
+	}
+	return append(out, namedValue{"map", r.MAP}, namedValue{"mrr", r.MRR})
+}
+
+// meanStd: media y desvío estándar muestral (n-1)
+func meanStd(xs []float64) (mean, std float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	if len(xs) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, x := range xs {
+		ss += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(xs)-1))
+}
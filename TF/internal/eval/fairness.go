@@ -0,0 +1,217 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Sesgo de popularidad y equidad -----------------
+
+// FairnessOptions: parámetros de EvaluateFairness
+type FairnessOptions struct {
+	K          int                 // largo de cada lista (0 -> 10)
+	SampleSize int                 // usuarios de la muestra (0 -> todos los de test)
+	Seed       int64               // semilla de la muestra
+	Threshold  float64             // rating de test relevante para recall (0 -> DefaultThreshold)
+	Tiers      *ml.PopularityTiers // franjas (nil -> NewPopularityTiers(train, 0, 0))
+	Groups     int                 // grupos de usuarios por actividad en train (0 -> 3)
+	Workers    int                 // goroutines (<= 0 -> runtime.NumCPU())
+}
+
+// TierStats: exposición y precisión de una franja de popularidad
+type TierStats struct {
+	Tier          string  `json:"tier"`
+	Items         int     `json:"items"`          // películas de la franja
+	CatalogShare  float64 `json:"catalog_share"`  // fracción del catálogo en la franja
+	ExposureShare float64 `json:"exposure_share"` // fracción de los lugares recomendados que ocupa
+	RMSE          float64 `json:"rmse"`           // pares de test de la franja (en estrellas)
+	Coverage      float64 `json:"coverage"`       // pares de test de la franja que el modelo pudo predecir
+	Recall        float64 `json:"recall"`         // relevantes de la franja que aparecen en el top-K
+}
+
+// GroupStats: calidad de las recomendaciones para un grupo de usuarios según actividad
+type GroupStats struct {
+	Group      string  `json:"group"` // "g1" = los menos activos
+	Users      int     `json:"users"`
+	MinRatings int     `json:"min_ratings"`
+	MaxRatings int     `json:"max_ratings"`
+	RMSE       float64 `json:"rmse"`
+	NDCG       float64 `json:"ndcg"`       // NDCG@K medio de los usuarios con relevantes
+	HeadShare  float64 `json:"head_share"` // fracción de sus listas que es head
+}
+
+// FairnessResult: análisis de sesgo de popularidad de un modelo
+type FairnessResult struct {
+	Model  string       `json:"model"`
+	K      int          `json:"k"`
+	Tiers  []TierStats  `json:"tiers"`
+	Groups []GroupStats `json:"groups"`
+}
+
+// userFairness: aportes de un usuario
+type userFairness struct {
+	exposure         [3]int // lugares de la lista por franja
+	se               [3]float64
+	predicted, total [3]int
+	hits, relevant   [3]int
+	ndcg             float64
+	hasRelevant      bool
+}
+
+// EvaluateFairness entrena rec sobre train y, para una muestra de usuarios de
+// test, mide cuánta exposición recibe cada franja de popularidad, qué tan bien
+// se predicen y recuperan sus películas, y cómo varía la calidad según la
+// actividad del usuario
+func EvaluateFairness(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, opts FairnessOptions) (FairnessResult, error) {
+	k := opts.K
+	if k <= 0 {
+		k = 10
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	nGroups := opts.Groups
+	if nGroups <= 0 {
+		nGroups = 3
+	}
+	tiers := opts.Tiers
+	if tiers == nil {
+		tiers = ml.NewPopularityTiers(train, 0, 0)
+	}
+	res := FairnessResult{Model: rec.Name(), K: k}
+	if err := rec.Fit(train); err != nil {
+		return res, err
+	}
+
+	users := sampleUsers(test, opts.SampleSize, opts.Seed)
+	perUser := make([]userFairness, len(users))
+	errs := make([]error, len(users))
+	forEachUser(ctx, users, opts.Workers, func(i, u int) {
+		uf := &perUser[i]
+		relevant := make(map[int]bool)
+		for it, r := range test.UserRatings[u] {
+			t := tiers.Of(it)
+			uf.total[t]++
+			if p, ok := rec.Predict(u, it); ok {
+				d := (p - r) * starScale
+				uf.se[t] += d * d
+				uf.predicted[t]++
+			}
+			if r >= threshold {
+				relevant[it] = true
+				uf.relevant[t]++
+			}
+		}
+
+		recs, err := rec.Recommend(ctx, u, ml.RecommendOptions{TopK: k})
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		for _, it := range recs {
+			t := tiers.Of(it.MovieID)
+			uf.exposure[t]++
+			if relevant[it.MovieID] {
+				uf.hits[t]++
+			}
+		}
+		if len(relevant) > 0 {
+			uf.hasRelevant = true
+			uf.ndcg = rankUser(recs, relevant, []int{k}).atK[0].NDCG
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return res, err
+		}
+	}
+
+	// por franja
+	var exposure, se [3]float64
+	var predicted, total, hits, relevant [3]int
+	for _, uf := range perUser {
+		for t := range 3 {
+			exposure[t] += float64(uf.exposure[t])
+			se[t] += uf.se[t]
+			predicted[t] += uf.predicted[t]
+			total[t] += uf.total[t]
+			hits[t] += uf.hits[t]
+			relevant[t] += uf.relevant[t]
+		}
+	}
+	slots := exposure[0] + exposure[1] + exposure[2]
+	catalog := len(tiers.Counts)
+	for _, t := range ml.Tiers {
+		ts := TierStats{Tier: t.String(), Items: tiers.Size(t)}
+		if catalog > 0 {
+			ts.CatalogShare = float64(ts.Items) / float64(catalog)
+		}
+		if slots > 0 {
+			ts.ExposureShare = exposure[t] / slots
+		}
+		if predicted[t] > 0 {
+			ts.RMSE = math.Sqrt(se[t] / float64(predicted[t]))
+		}
+		if total[t] > 0 {
+			ts.Coverage = float64(predicted[t]) / float64(total[t])
+		}
+		if relevant[t] > 0 {
+			ts.Recall = float64(hits[t]) / float64(relevant[t])
+		}
+		res.Tiers = append(res.Tiers, ts)
+	}
+
+	// por grupo de actividad: cuantiles de cantidad de ratings en train
+	activity := func(u int) int { return len(train.UserRatings[u]) }
+	order := make([]int, len(users))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return activity(users[order[a]]) < activity(users[order[b]]) })
+	for g := range nGroups {
+		lo, hi := g*len(order)/nGroups, (g+1)*len(order)/nGroups
+		if lo >= hi {
+			continue
+		}
+		gs := GroupStats{
+			Group:      fmt.Sprintf("g%d", g+1),
+			MinRatings: activity(users[order[lo]]),
+			MaxRatings: activity(users[order[hi-1]]),
+		}
+		gse, gpred, ndcgUsers := 0.0, 0, 0
+		headSlots, allSlots := 0, 0
+		for _, i := range order[lo:hi] {
+			uf := perUser[i]
+			gs.Users++
+			for t := range 3 {
+				gse += uf.se[t]
+				gpred += uf.predicted[t]
+				allSlots += uf.exposure[t]
+			}
+			headSlots += uf.exposure[ml.HeadTier]
+			if uf.hasRelevant {
+				gs.NDCG += uf.ndcg
+				ndcgUsers++
+			}
+		}
+		if gpred > 0 {
+			gs.RMSE = math.Sqrt(gse / float64(gpred))
+		}
+		if ndcgUsers > 0 {
+			gs.NDCG /= float64(ndcgUsers)
+		}
+		if allSlots > 0 {
+			gs.HeadShare = float64(headSlots) / float64(allSlots)
+		}
+		res.Groups = append(res.Groups, gs)
+	}
+	return res, nil
+}
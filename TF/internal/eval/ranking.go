@@ -0,0 +1,290 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Métricas de ranking (top-N) -----------------
+
+// Protocol: sobre qué candidatos se arma la lista a evaluar
+type Protocol int
+
+const (
+	// FullRanking: el modelo rankea todo el catálogo que el usuario no vio en train
+	FullRanking Protocol = iota
+	// SampledNegatives: el modelo rankea sólo los items de test del usuario más
+	// Negatives items al azar que el usuario nunca calificó
+	SampledNegatives
+)
+
+func (p Protocol) String() string {
+	if p == SampledNegatives {
+		return "sampled"
+	}
+	return "full"
+}
+
+// Valores por defecto de RankingOptions
+const (
+	DefaultThreshold = 0.8 // 4 estrellas en escala normalizada
+	DefaultNegatives = 100
+)
+
+// RankingOptions: parámetros de EvaluateRanking
+type RankingOptions struct {
+	Cutoffs   []int    // valores de k (vacío -> 5, 10, 20)
+	Threshold float64  // rating de test (normalizado) para ser relevante (0 -> DefaultThreshold)
+	Protocol  Protocol // FullRanking o SampledNegatives
+	Negatives int      // SampledNegatives: negativos por usuario (0 -> DefaultNegatives)
+	Seed      int64    // semilla de los negativos (cada usuario usa Seed+userId)
+	Workers   int      // goroutines (<= 0 -> runtime.NumCPU())
+}
+
+// CutoffMetrics: métricas a un corte k (medias sobre usuarios)
+type CutoffMetrics struct {
+	K         int     `json:"k"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	NDCG      float64 `json:"ndcg"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// RankingResult: métricas de ranking de un modelo. MAP y MRR se calculan sobre
+// la lista completa evaluada (el mayor de los cortes).
+type RankingResult struct {
+	Model     string          `json:"model"`
+	Protocol  string          `json:"protocol"`
+	Threshold float64         `json:"threshold"`
+	Users     int             `json:"users"` // usuarios con al menos un item relevante en test
+	MAP       float64         `json:"map"`
+	MRR       float64         `json:"mrr"`
+	AtK       []CutoffMetrics `json:"at_k"`
+	FitTime   time.Duration   `json:"fit_time_ns"`
+	EvalTime  time.Duration   `json:"eval_time_ns"`
+	PerUser   PerUser         `json:"-"` // mismas métricas por usuario (map = AP, mrr = RR)
+}
+
+// userRanking: métricas de un usuario (counted=false si no tiene relevantes)
+type userRanking struct {
+	atK     []CutoffMetrics
+	ap, rr  float64
+	counted bool
+}
+
+// EvaluateRanking entrena rec sobre train y, para cada usuario de test con algún
+// rating >= Threshold, compara su lista top-N con esos items relevantes
+func EvaluateRanking(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, opts RankingOptions) (RankingResult, error) {
+	if _, err := rankingCutoffs(opts); err != nil {
+		return RankingResult{}, err
+	}
+	start := time.Now()
+	if err := rec.Fit(train); err != nil {
+		return RankingResult{Model: rec.Name(), PerUser: make(PerUser)}, err
+	}
+	fitTime := time.Since(start)
+	res, err := ScoreRanking(ctx, rec, train, test, opts)
+	res.FitTime = fitTime
+	return res, err
+}
+
+// rankingCutoffs: los cortes de opts ordenados (por defecto 5, 10 y 20)
+func rankingCutoffs(opts RankingOptions) ([]int, error) {
+	cutoffs := append([]int(nil), opts.Cutoffs...)
+	if len(cutoffs) == 0 {
+		cutoffs = []int{5, 10, 20}
+	}
+	sort.Ints(cutoffs)
+	if cutoffs[0] <= 0 {
+		return nil, fmt.Errorf("eval: corte inválido %d", cutoffs[0])
+	}
+	return cutoffs, nil
+}
+
+// ScoreRanking: la parte de EvaluateRanking que no entrena (rec ya está
+// entrenado sobre train, que sólo se usa para muestrear negativos)
+func ScoreRanking(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, opts RankingOptions) (RankingResult, error) {
+	cutoffs, err := rankingCutoffs(opts)
+	if err != nil {
+		return RankingResult{}, err
+	}
+	maxK := cutoffs[len(cutoffs)-1]
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	negatives := opts.Negatives
+	if negatives <= 0 {
+		negatives = DefaultNegatives
+	}
+
+	res := RankingResult{Model: rec.Name(), Protocol: opts.Protocol.String(), Threshold: threshold, PerUser: make(PerUser)}
+	start := time.Now()
+	var catalog []int
+	if opts.Protocol == SampledNegatives {
+		catalog = sortedItems(train, test)
+	}
+	users := sortedUsers(test)
+	perUser := make([]userRanking, len(users))
+	errs := make([]error, len(users))
+	forEachUser(ctx, users, opts.Workers, func(i, u int) {
+		relevant := make(map[int]bool)
+		for it, r := range test.UserRatings[u] {
+			if r >= threshold {
+				relevant[it] = true
+			}
+		}
+		if len(relevant) == 0 {
+			return
+		}
+		ro := ml.RecommendOptions{TopK: maxK}
+		if opts.Protocol == SampledNegatives {
+			rng := rand.New(rand.NewSource(opts.Seed + int64(u)))
+			ro.Filter = ml.AllowList(sampleCandidates(rng, catalog, train.UserRatings[u], test.UserRatings[u], negatives)...)
+		}
+		recs, err := rec.Recommend(ctx, u, ro)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		perUser[i] = rankUser(recs, relevant, cutoffs)
+	})
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return res, err
+		}
+	}
+
+	res.AtK = make([]CutoffMetrics, len(cutoffs))
+	for c, k := range cutoffs {
+		res.AtK[c].K = k
+	}
+	for i, ur := range perUser {
+		if !ur.counted {
+			continue
+		}
+		for _, nv := range userRankingValues(ur) {
+			res.PerUser.add(nv.name, users[i], nv.value)
+		}
+		res.Users++
+		res.MAP += ur.ap
+		res.MRR += ur.rr
+		for c := range cutoffs {
+			res.AtK[c].Precision += ur.atK[c].Precision
+			res.AtK[c].Recall += ur.atK[c].Recall
+			res.AtK[c].NDCG += ur.atK[c].NDCG
+			res.AtK[c].HitRate += ur.atK[c].HitRate
+		}
+	}
+	if n := float64(res.Users); n > 0 {
+		res.MAP /= n
+		res.MRR /= n
+		for c := range cutoffs {
+			res.AtK[c].Precision /= n
+			res.AtK[c].Recall /= n
+			res.AtK[c].NDCG /= n
+			res.AtK[c].HitRate /= n
+		}
+	}
+	res.EvalTime = time.Since(start)
+	return res, nil
+}
+
+// rankUser: métricas de una lista contra los relevantes (ganancia binaria)
+func rankUser(recs []ml.ItemScore, relevant map[int]bool, cutoffs []int) userRanking {
+	ur := userRanking{atK: make([]CutoffMetrics, len(cutoffs)), counted: true}
+	maxK := cutoffs[len(cutoffs)-1]
+
+	hits, dcg, sumPrec := 0, 0.0, 0.0
+	c := 0
+	for pos := 0; pos < maxK; pos++ {
+		if pos < len(recs) && relevant[recs[pos].MovieID] {
+			hits++
+			dcg += 1 / math.Log2(float64(pos+2))
+			sumPrec += float64(hits) / float64(pos+1)
+			if ur.rr == 0 {
+				ur.rr = 1 / float64(pos+1)
+			}
+		}
+		for c < len(cutoffs) && cutoffs[c] == pos+1 {
+			k := cutoffs[c]
+			ur.atK[c] = CutoffMetrics{
+				K:         k,
+				Precision: float64(hits) / float64(k),
+				Recall:    float64(hits) / float64(len(relevant)),
+				NDCG:      dcg / idealDCG(min(len(relevant), k)),
+				HitRate:   b2f(hits > 0),
+			}
+			c++
+		}
+	}
+	ur.ap = sumPrec / float64(min(len(relevant), maxK))
+	return ur
+}
+
+// userRankingValues: métricas de un usuario con los mismos nombres que rankingValues
+func userRankingValues(ur userRanking) []namedValue {
+	return rankingValues(RankingResult{AtK: ur.atK, MAP: ur.ap, MRR: ur.rr})
+}
+
+// idealDCG: DCG de una lista con n relevantes arriba de todo
+func idealDCG(n int) float64 {
+	d := 0.0
+	for pos := 0; pos < n; pos++ {
+		d += 1 / math.Log2(float64(pos+2))
+	}
+	return d
+}
+
+// sampleCandidates: items de test del usuario + n negativos al azar que no
+// calificó ni en train ni en test
+func sampleCandidates(rng *rand.Rand, catalog []int, trainRatings, testRatings map[int]float64, n int) []int {
+	out := make([]int, 0, len(testRatings)+n)
+	for it := range testRatings {
+		out = append(out, it)
+	}
+	pool := make([]int, 0, len(catalog))
+	for _, it := range catalog {
+		_, inTrain := trainRatings[it]
+		_, inTest := testRatings[it]
+		if !inTrain && !inTest {
+			pool = append(pool, it)
+		}
+	}
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	return append(out, pool[:min(n, len(pool))]...)
+}
+
+// sortedItems: ids de película de todos los datasets, en orden ascendente
+func sortedItems(dss ...*ml.Dataset) []int {
+	set := make(map[int]struct{})
+	for _, ds := range dss {
+		for _, items := range ds.UserRatings {
+			for it := range items {
+				set[it] = struct{}{}
+			}
+		}
+	}
+	out := make([]int, 0, len(set))
+	for it := range set {
+		out = append(out, it)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Precisión de predicción de ratings -----------------
+
+// starScale: los ratings del Dataset están normalizados a 0..1; los errores se
+// reportan en estrellas (0..5) para que sean legibles
+const starScale = 5
+
+// RatingResult: métricas de predicción de un modelo sobre un split de test
+type RatingResult struct {
+	Model     string        `json:"model"`
+	Users     int           `json:"users"`     // usuarios de test con al menos un rating
+	Total     int           `json:"total"`     // pares (user, item) de test
+	Predicted int           `json:"predicted"` // pares que el modelo pudo predecir (ok=true)
+	RMSE      float64       `json:"rmse"`      // en estrellas, sólo sobre los pares predichos
+	MAE       float64       `json:"mae"`       // en estrellas, sólo sobre los pares predichos
+	Coverage  float64       `json:"coverage"`  // Predicted / Total
+	FitTime   time.Duration `json:"fit_time_ns"`
+	EvalTime  time.Duration `json:"eval_time_ns"`
+	PerUser   PerUser       `json:"-"` // rmse y mae de cada usuario con algún par predicho
+}
+
+// PerUser: valor de una métrica para cada usuario (métrica -> user -> valor),
+// la base de los tests de significancia pareados
+type PerUser map[string]map[int]float64
+
+func (p PerUser) add(metric string, user int, v float64) {
+	if p[metric] == nil {
+		p[metric] = make(map[int]float64)
+	}
+	p[metric][user] = v
+}
+
+// userErrors: acumulados de un usuario (se suman al final en orden de usuario
+// para que el resultado no dependa del reparto entre workers)
+type userErrors struct {
+	total, predicted int
+	se, ae           float64
+}
+
+// EvaluateRating entrena rec sobre train y predice cada rating de test, repartiendo
+// los usuarios entre workers goroutines (<= 0 -> runtime.NumCPU()).
+// Si ctx se cancela devuelve ctx.Err() sin resultado parcial.
+func EvaluateRating(ctx context.Context, rec ml.Recommender, train, test *ml.Dataset, workers int) (RatingResult, error) {
+	start := time.Now()
+	if err := rec.Fit(train); err != nil {
+		return RatingResult{Model: rec.Name(), PerUser: make(PerUser)}, err
+	}
+	fitTime := time.Since(start)
+	res, err := ScoreRating(ctx, rec, test, workers)
+	res.FitTime = fitTime
+	return res, err
+}
+
+// ScoreRating: la parte de EvaluateRating que no entrena (rec ya está entrenado)
+func ScoreRating(ctx context.Context, rec ml.Recommender, test *ml.Dataset, workers int) (RatingResult, error) {
+	res := RatingResult{Model: rec.Name(), PerUser: make(PerUser)}
+	start := time.Now()
+	users := sortedUsers(test)
+	perUser := make([]userErrors, len(users))
+	forEachUser(ctx, users, workers, func(i, u int) {
+		acc := &perUser[i]
+		for it, r := range test.UserRatings[u] {
+			acc.total++
+			p, ok := rec.Predict(u, it)
+			if !ok {
+				continue
+			}
+			d := (p - r) * starScale
+			acc.predicted++
+			acc.se += d * d
+			acc.ae += math.Abs(d)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	se, ae := 0.0, 0.0
+	for i, acc := range perUser {
+		if acc.total == 0 {
+			continue
+		}
+		if acc.predicted > 0 {
+			res.PerUser.add("rmse", users[i], math.Sqrt(acc.se/float64(acc.predicted)))
+			res.PerUser.add("mae", users[i], acc.ae/float64(acc.predicted))
+		}
+		res.Users++
+		res.Total += acc.total
+		res.Predicted += acc.predicted
+		se += acc.se
+		ae += acc.ae
+	}
+	if res.Predicted > 0 {
+		res.RMSE = math.Sqrt(se / float64(res.Predicted))
+		res.MAE = ae / float64(res.Predicted)
+	}
+	if res.Total > 0 {
+		res.Coverage = float64(res.Predicted) / float64(res.Total)
+	}
+	res.EvalTime = time.Since(start)
+	return res, nil
+}
+
+// forEachUser llama fn(i, users[i]) para cada usuario con workers goroutines;
+// deja de repartir trabajo en cuanto se cancela ctx
+func forEachUser(ctx context.Context, users []int, workers int, fn func(i, user int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i, users[i])
+			}
+		}()
+	}
+	for i := range users {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// sortedUsers: ids de usuario de ds en orden ascendente
+func sortedUsers(ds *ml.Dataset) []int {
+	users := make([]int, 0, len(ds.UserRatings))
+	for u := range ds.UserRatings {
+		users = append(users, u)
+	}
+	sort.Ints(users)
+	return users
+}
@@ -0,0 +1,261 @@
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Reportes -----------------
+
+// Table: resultados en forma tabular, una fila por modelo
+type Table struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// RatingTable arma la tabla de métricas de predicción
+func RatingTable(results []RatingResult) Table {
+	t := Table{
+		Title:  "Predicción de ratings (errores en estrellas)",
+		Header: []string{"modelo", "rmse", "mae", "cobertura", "predichos", "total", "fit", "eval"},
+	}
+	for _, r := range results {
+		t.Rows = append(t.Rows, []string{
+			r.Model,
+			ftoa(r.RMSE),
+			ftoa(r.MAE),
+			ftoa(r.Coverage),
+			strconv.Itoa(r.Predicted),
+			strconv.Itoa(r.Total),
+			r.FitTime.Round(1e6).String(),
+			r.EvalTime.Round(1e6).String(),
+		})
+	}
+	return t
+}
+
+// RankingTable arma la tabla de métricas top-N, una fila por modelo y corte
+func RankingTable(results []RankingResult) Table {
+	t := Table{
+		Title:  "Ranking top-N",
+		Header: []string{"modelo", "protocolo", "k", "precision", "recall", "ndcg", "hit_rate", "map", "mrr", "usuarios"},
+	}
+	for _, r := range results {
+		for _, m := range r.AtK {
+			t.Rows = append(t.Rows, []string{
+				r.Model,
+				r.Protocol,
+				strconv.Itoa(m.K),
+				ftoa(m.Precision),
+				ftoa(m.Recall),
+				ftoa(m.NDCG),
+				ftoa(m.HitRate),
+				ftoa(r.MAP),
+				ftoa(r.MRR),
+				strconv.Itoa(r.Users),
+			})
+		}
+	}
+	return t
+}
+
+// BeyondTable arma la tabla de métricas de cobertura, novedad y diversidad
+func BeyondTable(results []BeyondResult) Table {
+	t := Table{
+		Title:  "Más allá de la precisión",
+		Header: []string{"modelo", "k", "cobertura", "gini", "novedad", "rank_pop", "ild", "ild_métrica", "serendipia", "usuarios"},
+	}
+	for _, r := range results {
+		t.Rows = append(t.Rows, []string{
+			r.Model,
+			strconv.Itoa(r.K),
+			ftoa(r.CatalogCoverage),
+			ftoa(r.Gini),
+			ftoa(r.Novelty),
+			strconv.FormatFloat(r.MeanPopRank, 'f', 1, 64),
+			ftoa(r.ILD),
+			r.ILDMetric,
+			ftoa(r.Serendipity),
+			strconv.Itoa(r.Users),
+		})
+	}
+	return t
+}
+
+// CalibrationTable arma la tabla de calibración por géneros
+func CalibrationTable(results []CalibrationResult) Table {
+	t := Table{
+		Title:  "Calibración por géneros",
+		Header: []string{"modelo", "k", "miscalibración", "géneros_cubiertos", "usuarios"},
+	}
+	for _, r := range results {
+		t.Rows = append(t.Rows, []string{
+			r.Model,
+			strconv.Itoa(r.K),
+			ftoa(r.Miscalibration),
+			ftoa(r.GenreRecall),
+			strconv.Itoa(r.Users),
+		})
+	}
+	return t
+}
+
+// SequentialTable arma la tabla de los modelos de siguiente película (leave-last-out)
+func SequentialTable(results []ml.LeaveLastOutResult) Table {
+	t := Table{
+		Title:  "Siguiente película (leave-last-out)",
+		Header: []string{"modelo", "k", "hit_rate", "mrr", "usuarios"},
+	}
+	for _, r := range results {
+		t.Rows = append(t.Rows, []string{
+			r.Model,
+			strconv.Itoa(r.K),
+			ftoa(r.HitRate),
+			ftoa(r.MRR),
+			strconv.Itoa(r.Users),
+		})
+	}
+	return t
+}
+
+// CVTable arma la tabla de validación cruzada: media y desvío de cada métrica
+// por configuración, con los parámetros en columnas propias
+func CVTable(results []CVResult, params []string) Table {
+	t := Table{Title: "Validación cruzada (media ± desvío entre folds)", Header: []string{"config"}}
+	t.Header = append(t.Header, params...)
+	if len(results) > 0 {
+		for _, m := range results[0].Metrics {
+			t.Header = append(t.Header, m.Name, m.Name+"_std")
+		}
+	}
+	for _, r := range results {
+		row := []string{r.Config}
+		for _, p := range params {
+			row = append(row, r.Params[p])
+		}
+		for _, m := range r.Metrics {
+			row = append(row, ftoa(m.Mean), ftoa(m.Std))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// SignificanceTable arma la tabla de comparaciones pareadas contra el modelo de referencia
+func SignificanceTable(cmps []Comparison, correction Correction) Table {
+	t := Table{
+		Title:  fmt.Sprintf("Significancia (pareado por usuario, corrección %s)", correction),
+		Header: []string{"métrica", "referencia", "modelo", "n", "media_ref", "media", "diff", "ic_bajo", "ic_alto", "p_ttest", "p_wilcoxon", "p_ajustado", "d_z"},
+	}
+	for _, c := range cmps {
+		t.Rows = append(t.Rows, []string{
+			c.Metric,
+			c.ModelA,
+			c.ModelB,
+			strconv.Itoa(c.N),
+			ftoa(c.MeanA),
+			ftoa(c.MeanB),
+			ftoa(c.Diff),
+			ftoa(c.CILow),
+			ftoa(c.CIHigh),
+			ptoa(c.TTestP),
+			ptoa(c.WilcoxonP),
+			ptoa(c.AdjustedP),
+			ftoa(c.CohensDz),
+		})
+	}
+	return t
+}
+
+// FairnessTables arma dos tablas: por franja de popularidad y por grupo de actividad
+func FairnessTables(results []FairnessResult) (tiers, groups Table) {
+	tiers = Table{
+		Title:  "Sesgo de popularidad por franja",
+		Header: []string{"modelo", "franja", "items", "catálogo", "exposición", "rmse", "cobertura", "recall"},
+	}
+	groups = Table{
+		Title:  "Calidad por actividad del usuario",
+		Header: []string{"modelo", "grupo", "usuarios", "ratings_min", "ratings_max", "rmse", "ndcg", "head"},
+	}
+	for _, r := range results {
+		for _, t := range r.Tiers {
+			tiers.Rows = append(tiers.Rows, []string{
+				r.Model,
+				t.Tier,
+				strconv.Itoa(t.Items),
+				ftoa(t.CatalogShare),
+				ftoa(t.ExposureShare),
+				ftoa(t.RMSE),
+				ftoa(t.Coverage),
+				ftoa(t.Recall),
+			})
+		}
+		for _, g := range r.Groups {
+			groups.Rows = append(groups.Rows, []string{
+				r.Model,
+				g.Group,
+				strconv.Itoa(g.Users),
+				strconv.Itoa(g.MinRatings),
+				strconv.Itoa(g.MaxRatings),
+				ftoa(g.RMSE),
+				ftoa(g.NDCG),
+				ftoa(g.HeadShare),
+			})
+		}
+	}
+	return tiers, groups
+}
+
+// WriteText imprime la tabla alineada en columnas
+func WriteText(w io.Writer, t Table) error {
+	if t.Title != "" {
+		if _, err := fmt.Fprintf(w, "%s\n", t.Title); err != nil {
+			return err
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Header, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV escribe la tabla como CSV con encabezado
+func WriteCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteJSON escribe v (p.ej. []RatingResult) como JSON indentado
+func WriteJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func ftoa(x float64) string {
+	return strconv.FormatFloat(x, 'f', 4, 64)
+}
+
+// ptoa: p-valores chicos en notación científica para que no queden en 0.0000
+func ptoa(p float64) string {
+	if p > 0 && p < 1e-4 {
+		return strconv.FormatFloat(p, 'e', 1, 64)
+	}
+	return ftoa(p)
+}
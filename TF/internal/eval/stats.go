@@ -0,0 +1,319 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ----------------- Significancia estadística -----------------
+
+// Correction: corrección por comparaciones múltiples
+type Correction int
+
+const (
+	NoCorrection Correction = iota
+	HolmCorrection
+	BonferroniCorrection
+)
+
+func (c Correction) String() string {
+	switch c {
+	case HolmCorrection:
+		return "holm"
+	case BonferroniCorrection:
+		return "bonferroni"
+	default:
+		return "none"
+	}
+}
+
+// ParseCorrection: "none", "holm" o "bonferroni"
+func ParseCorrection(s string) (Correction, error) {
+	for _, c := range []Correction{NoCorrection, HolmCorrection, BonferroniCorrection} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("eval: corrección desconocida %q (usa none, holm o bonferroni)", s)
+}
+
+// Comparison: B contra A sobre los usuarios evaluados por ambos
+type Comparison struct {
+	Metric    string  `json:"metric"`
+	ModelA    string  `json:"model_a"` // referencia
+	ModelB    string  `json:"model_b"`
+	N         int     `json:"n"` // usuarios pareados
+	MeanA     float64 `json:"mean_a"`
+	MeanB     float64 `json:"mean_b"`
+	Diff      float64 `json:"diff"` // media de B-A
+	CILow     float64 `json:"ci_low"`
+	CIHigh    float64 `json:"ci_high"`
+	TTestP    float64 `json:"ttest_p"`
+	WilcoxonP float64 `json:"wilcoxon_p"`
+	CohensDz  float64 `json:"cohens_dz"`  // tamaño del efecto: media(d)/desvío(d)
+	AdjustedP float64 `json:"adjusted_p"` // p del t-test corregido por comparaciones múltiples
+}
+
+// CompareOptions: parámetros de Compare
+type CompareOptions struct {
+	Bootstrap  int        // remuestreos del intervalo de confianza (0 -> 1000)
+	Confidence float64    // nivel del intervalo (0 -> 0.95)
+	Seed       int64      // semilla del bootstrap
+	Correction Correction // aplicada sobre todas las comparaciones de CompareAll
+}
+
+// Compare contrasta los valores por usuario de metric de dos modelos con un
+// t-test pareado, Wilcoxon signed-rank y un intervalo bootstrap de la diferencia.
+// Sólo usa los usuarios presentes en ambos.
+func Compare(metric, modelA string, a PerUser, modelB string, b PerUser, opts CompareOptions) Comparison {
+	c := Comparison{Metric: metric, ModelA: modelA, ModelB: modelB}
+	xs, ys := paired(a[metric], b[metric])
+	c.N = len(xs)
+	if c.N == 0 {
+		c.TTestP, c.WilcoxonP, c.AdjustedP = 1, 1, 1
+		return c
+	}
+	d := make([]float64, c.N)
+	for i := range xs {
+		c.MeanA += xs[i]
+		c.MeanB += ys[i]
+		d[i] = ys[i] - xs[i]
+	}
+	c.MeanA /= float64(c.N)
+	c.MeanB /= float64(c.N)
+
+	mean, sd := meanStd(d)
+	c.Diff = mean
+	if sd > 0 {
+		c.CohensDz = mean / sd
+	}
+	c.TTestP = pairedTTest(d)
+	c.WilcoxonP = wilcoxonSignedRank(d)
+	c.AdjustedP = c.TTestP
+
+	iters := opts.Bootstrap
+	if iters <= 0 {
+		iters = 1000
+	}
+	conf := opts.Confidence
+	if conf <= 0 || conf >= 1 {
+		conf = 0.95
+	}
+	c.CILow, c.CIHigh = bootstrapCI(d, iters, conf, opts.Seed)
+	return c
+}
+
+// CompareAll compara cada uno de others contra base en cada métrica y corrige
+// los p-valores del t-test sobre el total de comparaciones
+func CompareAll(metrics []string, baseName string, base PerUser, names []string, others []PerUser, opts CompareOptions) []Comparison {
+	var out []Comparison
+	for _, m := range metrics {
+		for i := range others {
+			out = append(out, Compare(m, baseName, base, names[i], others[i], opts))
+		}
+	}
+	p := make([]float64, len(out))
+	for i := range out {
+		p[i] = out[i].TTestP
+	}
+	for i, adj := range AdjustPValues(p, opts.Correction) {
+		out[i].AdjustedP = adj
+	}
+	return out
+}
+
+// AdjustPValues: p-valores corregidos (Holm step-down o Bonferroni), en el orden original
+func AdjustPValues(p []float64, c Correction) []float64 {
+	out := append([]float64(nil), p...)
+	m := float64(len(p))
+	switch c {
+	case BonferroniCorrection:
+		for i := range out {
+			out[i] = math.Min(1, p[i]*m)
+		}
+	case HolmCorrection:
+		idx := make([]int, len(p))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(i, j int) bool { return p[idx[i]] < p[idx[j]] })
+		running := 0.0
+		for rank, i := range idx {
+			running = math.Max(running, math.Min(1, p[i]*(m-float64(rank))))
+			out[i] = running
+		}
+	}
+	return out
+}
+
+// paired: valores de los usuarios presentes en ambos mapas, en orden de usuario
+func paired(a, b map[int]float64) (xs, ys []float64) {
+	users := make([]int, 0, len(a))
+	for u := range a {
+		if _, ok := b[u]; ok {
+			users = append(users, u)
+		}
+	}
+	sort.Ints(users)
+	for _, u := range users {
+		xs = append(xs, a[u])
+		ys = append(ys, b[u])
+	}
+	return xs, ys
+}
+
+// pairedTTest: p-valor bilateral del t-test sobre las diferencias d
+func pairedTTest(d []float64) float64 {
+	n := len(d)
+	if n < 2 {
+		return 1
+	}
+	mean, sd := meanStd(d)
+	if sd == 0 {
+		if mean == 0 {
+			return 1
+		}
+		return 0
+	}
+	t := mean / (sd / math.Sqrt(float64(n)))
+	df := float64(n - 1)
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// wilcoxonSignedRank: p-valor bilateral con aproximación normal (descarta las
+// diferencias nulas, rangos promedio en empates, corrección por empates y de continuidad)
+func wilcoxonSignedRank(d []float64) float64 {
+	nz := make([]float64, 0, len(d))
+	for _, x := range d {
+		if x != 0 {
+			nz = append(nz, x)
+		}
+	}
+	n := len(nz)
+	if n == 0 {
+		return 1
+	}
+	sort.Slice(nz, func(i, j int) bool { return math.Abs(nz[i]) < math.Abs(nz[j]) })
+
+	wPlus, tieTerm := 0.0, 0.0
+	for i := 0; i < n; {
+		j := i
+		for j < n && math.Abs(nz[j]) == math.Abs(nz[i]) {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // rangos i+1..j
+		for k := i; k < j; k++ {
+			if nz[k] > 0 {
+				wPlus += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+	nf := float64(n)
+	mu := nf * (nf + 1) / 4
+	sigma := math.Sqrt(nf*(nf+1)*(2*nf+1)/24 - tieTerm/48)
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(wPlus-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// bootstrapCI: intervalo percentil de la media de d
+func bootstrapCI(d []float64, iters int, conf float64, seed int64) (lo, hi float64) {
+	rng := rand.New(rand.NewSource(seed))
+	means := make([]float64, iters)
+	for it := range means {
+		sum := 0.0
+		for range d {
+			sum += d[rng.Intn(len(d))]
+		}
+		means[it] = sum / float64(len(d))
+	}
+	sort.Float64s(means)
+	alpha := (1 - conf) / 2
+	return quantile(means, alpha), quantile(means, 1-alpha)
+}
+
+// quantile: cuantil q de xs ordenado (interpolación lineal)
+func quantile(xs []float64, q float64) float64 {
+	pos := q * float64(len(xs)-1)
+	i := int(pos)
+	if i+1 >= len(xs) {
+		return xs[len(xs)-1]
+	}
+	return xs[i] + (pos-float64(i))*(xs[i+1]-xs[i])
+}
+
+// regIncBeta: función beta incompleta regularizada I_x(a,b) (fracción continua
+// de Lentz), usada para la distribución t de Student
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// la fracción converge rápido para x < (a+1)/(a+b+2); si no, simetría
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaCF(b, a, 1-x)/b
+	}
+	return front * betaCF(a, b, x) / a
+}
+
+func betaCF(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		mf := float64(m)
+		// paso par
+		num := mf * (b - mf) * x / ((a + 2*mf - 1) * (a + 2*mf))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// paso impar
+		num = -(a + mf) * (a + b + mf) * x / ((a + 2*mf) * (a + 2*mf + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return h
+}
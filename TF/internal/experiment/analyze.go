@@ -0,0 +1,204 @@
+package experiment
+
+import (
+	"math"
+	"sort"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Análisis de resultados -----------------
+
+// InterleavingResult: preferencia entre A y B en las impresiones intercaladas
+// de un experimento. Sólo cuentan como duelo las impresiones con algún click.
+type InterleavingResult struct {
+	Experiment  string  `json:"experiment"`
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	Impressions int     `json:"impressions"`
+	WithClicks  int     `json:"with_clicks"`
+	WinsA       int     `json:"wins_a"`
+	WinsB       int     `json:"wins_b"`
+	Ties        int     `json:"ties"`
+	WinRateA    float64 `json:"win_rate_a"` // WinsA / (WinsA+WinsB)
+	CILow       float64 `json:"ci_low"`     // intervalo de Wilson de WinRateA
+	CIHigh      float64 `json:"ci_high"`
+	Preference  float64 `json:"preference"` // (WinsA + Ties/2)/WithClicks - 0.5: > 0 favorece a A
+	PValue      float64 `json:"p_value"`    // sign test bilateral (empates descartados)
+}
+
+// ArmResult: clicks de una variante de un A/B
+type ArmResult struct {
+	Experiment  string  `json:"experiment"`
+	Arm         string  `json:"arm"`
+	Users       int     `json:"users"`
+	Impressions int     `json:"impressions"`
+	Clicked     int     `json:"clicked"` // impresiones con algún click
+	Clicks      int     `json:"clicks"`
+	CTR         float64 `json:"ctr"` // Clicked / Impressions
+	CILow       float64 `json:"ci_low"`
+	CIHigh      float64 `json:"ci_high"`
+}
+
+// Analysis: resultados de todos los experimentos del log
+type Analysis struct {
+	Confidence   float64              `json:"confidence"`
+	Interleaving []InterleavingResult `json:"interleaving,omitempty"`
+	Arms         []ArmResult          `json:"arms,omitempty"`
+	Orphans      int                  `json:"orphan_clicks"` // clicks sin su impresión en el log
+}
+
+// Analyze agrupa los clicks por impresión y calcula, por experimento, la tasa de
+// victorias de A en los intercalados y el CTR de cada variante en los A/B, con
+// intervalos de confianza de Wilson al nivel confidence (0 -> 0.95)
+func Analyze(events []Event, confidence float64) Analysis {
+	if confidence <= 0 || confidence >= 1 {
+		confidence = 0.95
+	}
+	res := Analysis{Confidence: confidence}
+	z := math.Sqrt2 * math.Erfinv(confidence)
+
+	impressions := make(map[string]*Event)
+	var order []string
+	clicks := make(map[string][]int)
+	for i := range events {
+		ev := &events[i]
+		switch ev.Type {
+		case ImpressionEvent:
+			if _, ok := impressions[ev.Impression]; !ok {
+				order = append(order, ev.Impression)
+			}
+			impressions[ev.Impression] = ev
+		case ClickEvent:
+			clicks[ev.Impression] = append(clicks[ev.Impression], ev.Item)
+		}
+	}
+	for id, cs := range clicks {
+		if _, ok := impressions[id]; !ok {
+			res.Orphans += len(cs)
+		}
+	}
+
+	type key struct{ exp, a, b string }
+	duels := make(map[key]*InterleavingResult)
+	arms := make(map[key]*ArmResult)
+	armUsers := make(map[key]map[int]bool)
+	for _, id := range order {
+		ev := impressions[id]
+		if len(ev.Teams) > 0 {
+			k := key{ev.Experiment, ev.A, ev.B}
+			r := duels[k]
+			if r == nil {
+				r = &InterleavingResult{Experiment: ev.Experiment, A: ev.A, B: ev.B}
+				duels[k] = r
+			}
+			r.Impressions++
+			il := Interleaved{Teams: ev.Teams}
+			for _, it := range ev.Items {
+				il.Items = append(il.Items, ml.ItemScore{MovieID: it})
+			}
+			switch il.Outcome(clicks[id]) {
+			case WinA:
+				r.WinsA++
+			case WinB:
+				r.WinsB++
+			case Tie:
+				r.Ties++
+			}
+			continue
+		}
+		k := key{exp: ev.Experiment, a: ev.Arm}
+		r := arms[k]
+		if r == nil {
+			r = &ArmResult{Experiment: ev.Experiment, Arm: ev.Arm}
+			arms[k] = r
+			armUsers[k] = make(map[int]bool)
+		}
+		armUsers[k][ev.User] = true
+		r.Impressions++
+		shown := make(map[int]bool, len(ev.Items))
+		for _, it := range ev.Items {
+			shown[it] = true
+		}
+		n := 0
+		for _, it := range clicks[id] {
+			if shown[it] {
+				n++
+			}
+		}
+		r.Clicks += n
+		if n > 0 {
+			r.Clicked++
+		}
+	}
+
+	for _, r := range duels {
+		r.WithClicks = r.WinsA + r.WinsB + r.Ties
+		duel := r.WinsA + r.WinsB
+		if duel > 0 {
+			r.WinRateA = float64(r.WinsA) / float64(duel)
+		}
+		r.CILow, r.CIHigh = wilson(r.WinsA, duel, z)
+		if r.WithClicks > 0 {
+			r.Preference = (float64(r.WinsA)+float64(r.Ties)/2)/float64(r.WithClicks) - 0.5
+		}
+		r.PValue = signTest(r.WinsA, r.WinsB)
+		res.Interleaving = append(res.Interleaving, *r)
+	}
+	for k, r := range arms {
+		r.Users = len(armUsers[k])
+		if r.Impressions > 0 {
+			r.CTR = float64(r.Clicked) / float64(r.Impressions)
+		}
+		r.CILow, r.CIHigh = wilson(r.Clicked, r.Impressions, z)
+		res.Arms = append(res.Arms, *r)
+	}
+	sort.Slice(res.Interleaving, func(i, j int) bool {
+		a, b := res.Interleaving[i], res.Interleaving[j]
+		if a.Experiment != b.Experiment {
+			return a.Experiment < b.Experiment
+		}
+		if a.A != b.A {
+			return a.A < b.A
+		}
+		return a.B < b.B
+	})
+	sort.Slice(res.Arms, func(i, j int) bool {
+		a, b := res.Arms[i], res.Arms[j]
+		if a.Experiment != b.Experiment {
+			return a.Experiment < b.Experiment
+		}
+		return a.Arm < b.Arm
+	})
+	return res
+}
+
+// wilson: intervalo de Wilson para k éxitos en n ensayos (z = cuantil normal)
+func wilson(k, n int, z float64) (lo, hi float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	nf := float64(n)
+	p := float64(k) / nf
+	den := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / den
+	half := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / den
+	return max(0, center-half), min(1, center+half)
+}
+
+// signTest: p-valor bilateral exacto de Binomial(a+b, 1/2) para a victorias contra b
+func signTest(a, b int) float64 {
+	n := a + b
+	if n == 0 {
+		return 1
+	}
+	k := min(a, b)
+	lgn, _ := math.Lgamma(float64(n + 1))
+	tail := 0.0
+	for i := 0; i <= k; i++ {
+		li, _ := math.Lgamma(float64(i + 1))
+		lr, _ := math.Lgamma(float64(n - i + 1))
+		tail += math.Exp(lgn - li - lr - float64(n)*math.Ln2)
+	}
+	return min(1, 2*tail)
+}
@@ -0,0 +1,88 @@
+package experiment
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+)
+
+// ----------------- Asignación de usuarios -----------------
+
+// Arm: una variante del experimento y su fracción del tráfico (peso relativo)
+type Arm struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// Experiment: reparte usuarios entre variantes de forma determinista. El mismo
+// usuario cae siempre en la misma variante de un experimento, y experimentos con
+// nombre distinto reparten de forma independiente.
+type Experiment struct {
+	Name string `json:"name"`
+	Arms []Arm  `json:"arms"`
+}
+
+// NewExperiment crea un experimento con las variantes en partes iguales
+func NewExperiment(name string, arms ...string) (*Experiment, error) {
+	if len(arms) == 0 {
+		return nil, errors.New("experiment: sin variantes")
+	}
+	e := &Experiment{Name: name}
+	for _, a := range arms {
+		e.Arms = append(e.Arms, Arm{Name: a, Weight: 1})
+	}
+	return e, nil
+}
+
+// Hash: FNV-1a de 64 bits de "salt:user" pasado por el mezclado final de
+// MurmurHash3. FNV solo reparte mal ids consecutivos: el bit bajo sigue la
+// paridad del usuario y los altos se cargan hacia un lado.
+func Hash(salt string, user int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(salt))
+	h.Write([]byte{':'})
+	h.Write([]byte(strconv.Itoa(user)))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Bucket: balde 0..n-1 del usuario para salt
+func Bucket(salt string, user, n int) int {
+	return int(Hash(salt, user) % uint64(n))
+}
+
+// unit: hash del usuario llevado a [0,1)
+func unit(salt string, user int) float64 {
+	return float64(Hash(salt, user)>>11) / (1 << 53)
+}
+
+// Assign devuelve la variante del usuario ("" si no hay variantes con peso)
+func (e *Experiment) Assign(user int) string {
+	total := 0.0
+	for _, a := range e.Arms {
+		if a.Weight > 0 {
+			total += a.Weight
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	x := unit(e.Name, user) * total
+	last := ""
+	for _, a := range e.Arms {
+		if a.Weight <= 0 {
+			continue
+		}
+		if x < a.Weight {
+			return a.Name
+		}
+		x -= a.Weight
+		last = a.Name
+	}
+	return last // redondeo
+}
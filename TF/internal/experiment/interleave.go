@@ -0,0 +1,139 @@
+package experiment
+
+import (
+	"math/rand"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Team-draft interleaving -----------------
+
+// Team: de qué lista salió cada posición de la lista intercalada
+type Team string
+
+const (
+	TeamA Team = "A"
+	TeamB Team = "B"
+)
+
+// Interleaved: lista mezclada y el equipo dueño de cada posición
+type Interleaved struct {
+	Items []ml.ItemScore `json:"items"`
+	Teams []Team         `json:"teams"`
+}
+
+// TeamDraft intercala a y b según Radlinski et al. (2008): en cada ronda el
+// equipo con menos elegidos (al azar si empatan) toma su mejor película que
+// todavía no esté en la lista. Para resultados reproducibles por usuario usar
+// NewRand(salt, user).
+func TeamDraft(a, b []ml.ItemScore, k int, rng *rand.Rand) Interleaved {
+	var out Interleaved
+	used := make(map[int]bool)
+	ia, ib := 0, 0
+	na, nb := 0, 0
+	// next: siguiente película de list desde *i que no esté usada
+	next := func(list []ml.ItemScore, i *int) (ml.ItemScore, bool) {
+		for *i < len(list) {
+			it := list[*i]
+			*i++
+			if !used[it.MovieID] {
+				return it, true
+			}
+		}
+		return ml.ItemScore{}, false
+	}
+	for len(out.Items) < k {
+		pickA := na < nb || (na == nb && rng.Intn(2) == 0)
+		it, ok := ml.ItemScore{}, false
+		if pickA {
+			if it, ok = next(a, &ia); !ok {
+				it, ok = next(b, &ib)
+				pickA = false
+			}
+		} else {
+			if it, ok = next(b, &ib); !ok {
+				it, ok = next(a, &ia)
+				pickA = true
+			}
+		}
+		if !ok {
+			break
+		}
+		used[it.MovieID] = true
+		out.Items = append(out.Items, it)
+		if pickA {
+			out.Teams = append(out.Teams, TeamA)
+			na++
+		} else {
+			out.Teams = append(out.Teams, TeamB)
+			nb++
+		}
+	}
+	return out
+}
+
+// NewRand: generador determinista para el usuario (mismo salt y usuario -> mismo intercalado)
+func NewRand(salt string, user int) *rand.Rand {
+	return rand.New(rand.NewSource(int64(Hash(salt, user))))
+}
+
+// Outcome: resultado de una impresión intercalada
+type Outcome int
+
+const (
+	NoClicks Outcome = iota
+	WinA
+	WinB
+	Tie
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case WinA:
+		return "A"
+	case WinB:
+		return "B"
+	case Tie:
+		return "tie"
+	default:
+		return "none"
+	}
+}
+
+// Credit atribuye cada click (por MovieID) al equipo de su posición; los clicks
+// sobre películas que no están en la lista se ignoran
+func (il Interleaved) Credit(clicks []int) (a, b int) {
+	team := make(map[int]Team, len(il.Items))
+	for i, it := range il.Items {
+		team[it.MovieID] = il.Teams[i]
+	}
+	seen := make(map[int]bool, len(clicks))
+	for _, id := range clicks {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		switch team[id] {
+		case TeamA:
+			a++
+		case TeamB:
+			b++
+		}
+	}
+	return a, b
+}
+
+// Outcome: gana el equipo con más clicks atribuidos
+func (il Interleaved) Outcome(clicks []int) Outcome {
+	a, b := il.Credit(clicks)
+	switch {
+	case a == 0 && b == 0:
+		return NoClicks
+	case a > b:
+		return WinA
+	case b > a:
+		return WinB
+	default:
+		return Tie
+	}
+}
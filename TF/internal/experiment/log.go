@@ -0,0 +1,138 @@
+package experiment
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ----------------- Registro de impresiones y clicks -----------------
+
+// EventType: "impression" o "click"
+type EventType string
+
+const (
+	ImpressionEvent EventType = "impression"
+	ClickEvent      EventType = "click"
+)
+
+// Event: una línea del log JSONL. Una impresión trae la lista mostrada y, si
+// fue intercalada, el equipo de cada posición y los nombres de A y B; en un
+// A/B trae la variante (Arm). Un click referencia su impresión por ID.
+type Event struct {
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	Experiment string    `json:"experiment"`
+	Impression string    `json:"impression"` // ID de la impresión
+	User       int       `json:"user"`
+
+	// impresión
+	Arm   string `json:"arm,omitempty"`
+	A     string `json:"a,omitempty"`
+	B     string `json:"b,omitempty"`
+	Items []int  `json:"items,omitempty"`
+	Teams []Team `json:"teams,omitempty"`
+
+	// click
+	Item     int `json:"item,omitempty"`
+	Position int `json:"position,omitempty"` // 1-based, 0 si no se conoce
+}
+
+// Logger escribe eventos como JSON por línea; es seguro para varias goroutines
+type Logger struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	enc *json.Encoder
+	seq int
+	run string // prefijo de los IDs de esta corrida
+
+	Now func() time.Time // reloj (nil -> time.Now)
+}
+
+// NewLogger crea un logger sobre w (llamar Flush al terminar)
+func NewLogger(w io.Writer) *Logger {
+	bw := bufio.NewWriter(w)
+	return &Logger{w: bw, enc: json.NewEncoder(bw), run: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+func (l *Logger) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// Interleaved registra una impresión intercalada de a contra b y devuelve su ID
+func (l *Logger) Interleaved(experiment string, user int, a, b string, il Interleaved) (string, error) {
+	ev := Event{Type: ImpressionEvent, Experiment: experiment, User: user, A: a, B: b, Teams: il.Teams}
+	for _, it := range il.Items {
+		ev.Items = append(ev.Items, it.MovieID)
+	}
+	return l.impression(ev)
+}
+
+// Shown registra una impresión de la variante arm (A/B) y devuelve su ID
+func (l *Logger) Shown(experiment string, user int, arm string, items []int) (string, error) {
+	return l.impression(Event{Type: ImpressionEvent, Experiment: experiment, User: user, Arm: arm, Items: items})
+}
+
+func (l *Logger) impression(ev Event) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	ev.Impression = l.run + "-" + strconv.Itoa(l.seq)
+	ev.Time = l.now()
+	return ev.Impression, l.enc.Encode(ev)
+}
+
+// Click registra un click del usuario sobre item en la impresión indicada
+func (l *Logger) Click(experiment, impression string, user, item, position int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(Event{
+		Type:       ClickEvent,
+		Time:       l.now(),
+		Experiment: experiment,
+		Impression: impression,
+		User:       user,
+		Item:       item,
+		Position:   position,
+	})
+}
+
+// Flush vacía el buffer al writer
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Flush()
+}
+
+// ReadEvents lee un log JSONL (las líneas vacías se ignoran)
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("experiment: línea %d: %w", line, err)
+		}
+		if ev.Type != ImpressionEvent && ev.Type != ClickEvent {
+			return nil, fmt.Errorf("experiment: línea %d: tipo de evento desconocido %q", line, ev.Type)
+		}
+		if ev.Impression == "" {
+			return nil, fmt.Errorf("experiment: línea %d: evento sin impression", line)
+		}
+		events = append(events, ev)
+	}
+	return events, sc.Err()
+}
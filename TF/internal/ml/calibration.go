@@ -0,0 +1,162 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// ----------------- Calibración por géneros -----------------
+
+// DefaultCalibrationAlpha: suavizado de q en la KL para que no diverja cuando
+// la lista no tiene un género que el usuario sí ve
+const DefaultCalibrationAlpha = 0.01
+
+// GenreDistribution: distribución de géneros de un conjunto de películas
+// (item -> peso). Cada película reparte su peso en partes iguales entre sus
+// géneros; las que no están en el catálogo o no tienen géneros no suman.
+func GenreDistribution(cat *Catalog, items map[int]float64) map[string]float64 {
+	dist := make(map[string]float64)
+	total := 0.0
+	for it, w := range items {
+		genres := cat.Genres(it)
+		if len(genres) == 0 || w <= 0 {
+			continue
+		}
+		for _, g := range genres {
+			dist[g] += w / float64(len(genres))
+		}
+		total += w
+	}
+	if total > 0 {
+		for g := range dist {
+			dist[g] /= total
+		}
+	}
+	return dist
+}
+
+// Miscalibration: KL(p || q~) con q~ = (1-alpha)*q + alpha*p (Steck, 2018).
+// 0 si la lista reproduce exactamente la distribución del usuario.
+func Miscalibration(p, q map[string]float64, alpha float64) float64 {
+	kl := 0.0
+	for g, pg := range p {
+		if pg <= 0 {
+			continue
+		}
+		qg := (1-alpha)*q[g] + alpha*pg
+		kl += pg * math.Log(pg/qg)
+	}
+	return kl
+}
+
+// Calibrated: re-ranker de Steck (2018). Arma el top-K de forma greedy eligiendo
+// en cada paso el candidato que maximiza
+//
+//	(1-Lambda)*sum relevancia(lista) - Lambda*KL(p(g|u) || q~(g|lista))
+//
+// con p(g|u) la distribución de géneros de lo que el usuario calificó y la
+// relevancia normalizada a [0,1]. Lambda=0 deja el orden original. Necesita Fit
+// (lo llama Reranked.Fit).
+type Calibrated struct {
+	Catalog        *Catalog
+	Lambda         float64
+	Alpha          float64 // suavizado de la KL (0 -> DefaultCalibrationAlpha)
+	RatingWeighted bool    // pondera la historia por el rating en vez de contar 1 por película
+
+	ds *Dataset
+}
+
+// NewCalibrated crea el re-ranker con el peso de la calibración
+func NewCalibrated(cat *Catalog, lambda float64) *Calibrated {
+	return &Calibrated{Catalog: cat, Lambda: lambda}
+}
+
+func (r *Calibrated) Name() string {
+	return fmt.Sprintf("calibrated(λ=%.2f)", r.Lambda)
+}
+
+func (r *Calibrated) Fit(ds *Dataset) error {
+	r.ds = ds
+	return nil
+}
+
+func (r *Calibrated) alpha() float64 {
+	if r.Alpha <= 0 {
+		return DefaultCalibrationAlpha
+	}
+	return r.Alpha
+}
+
+// UserDistribution: p(g|u) según la historia del usuario en el dataset de Fit
+func (r *Calibrated) UserDistribution(user int) map[string]float64 {
+	hist := make(map[int]float64, len(r.ds.UserRatings[user]))
+	for it, rating := range r.ds.UserRatings[user] {
+		if r.RatingWeighted {
+			hist[it] = rating
+		} else {
+			hist[it] = 1
+		}
+	}
+	return GenreDistribution(r.Catalog, hist)
+}
+
+func (r *Calibrated) Rerank(user int, items []ItemScore, k int) []ItemScore {
+	if r.ds == nil || r.Catalog == nil {
+		return truncate(items, k)
+	}
+	p := r.UserDistribution(user)
+	if len(p) == 0 {
+		return truncate(items, k)
+	}
+	k = min(k, len(items))
+	alpha := r.alpha()
+
+	rel := normalizeScores(items)
+	used := make([]bool, len(items))
+	out := make([]ItemScore, 0, k)
+	mass := make(map[string]float64) // masa de géneros de la lista (sin normalizar)
+	listed := 0.0                    // películas de la lista con géneros
+	relSum := 0.0
+
+	q := make(map[string]float64)
+	for len(out) < k {
+		best, bestVal := -1, math.Inf(-1)
+		for i, it := range items {
+			if used[i] {
+				continue
+			}
+			genres := r.Catalog.Genres(it.MovieID)
+			n := listed
+			if len(genres) > 0 {
+				n++
+			}
+			clear(q)
+			for g, m := range mass {
+				q[g] = m
+			}
+			for _, g := range genres {
+				q[g] += 1 / float64(len(genres))
+			}
+			if n > 0 {
+				for g := range q {
+					q[g] /= n
+				}
+			}
+			val := (1-r.Lambda)*(relSum+rel[it.MovieID]) - r.Lambda*Miscalibration(p, q, alpha)
+			if val > bestVal {
+				best, bestVal = i, val
+			}
+		}
+		used[best] = true
+		id := items[best].MovieID
+		out = append(out, items[best])
+		relSum += rel[id]
+		if genres := r.Catalog.Genres(id); len(genres) > 0 {
+			for _, g := range genres {
+				mass[g] += 1 / float64(len(genres))
+			}
+			listed++
+		}
+	}
+	return out
+}
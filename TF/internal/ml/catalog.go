@@ -0,0 +1,120 @@
+package ml
+
+import (
+	"encoding/csv"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Movie: metadatos de una película (movies.csv + tags.csv)
+type Movie struct {
+	ID     int
+	Title  string
+	Year   int // 0 si el título no trae "(yyyy)"
+	Genres []string
+	Tags   map[string]int // tag normalizado -> cuántas veces se aplicó
+}
+
+// Decade: década de estreno (1990, 2000, ...), 0 si no se conoce el año
+func (m *Movie) Decade() int {
+	return m.Year / 10 * 10
+}
+
+// Catalog: películas por movieId
+type Catalog struct {
+	Movies map[int]*Movie
+}
+
+var yearRe = regexp.MustCompile(`\((\d{4})\)\s*$`)
+
+// LoadCatalog lee movies.csv (movieId,title,genres) y, si tagsPath no es "",
+// tags.csv (userId,movieId,tag,timestamp)
+func LoadCatalog(moviesPath, tagsPath string) (*Catalog, error) {
+	f, err := os.Open(moviesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	_, _ = r.Read() // skip header
+
+	cat := &Catalog{Movies: make(map[int]*Movie)}
+	for {
+		row, err := r.Read()
+		if err != nil {
+			break
+		}
+		if len(row) < 3 {
+			continue
+		}
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			continue
+		}
+		mv := &Movie{ID: id, Title: strings.TrimSpace(row[1]), Tags: map[string]int{}}
+		if m := yearRe.FindStringSubmatch(mv.Title); m != nil {
+			mv.Year, _ = strconv.Atoi(m[1])
+		}
+		if row[2] != "(no genres listed)" {
+			for _, g := range strings.Split(row[2], "|") {
+				if g = strings.TrimSpace(g); g != "" {
+					mv.Genres = append(mv.Genres, g)
+				}
+			}
+		}
+		cat.Movies[id] = mv
+	}
+
+	if tagsPath == "" {
+		return cat, nil
+	}
+	if err := cat.loadTags(tagsPath); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+func (c *Catalog) loadTags(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	_, _ = r.Read() // skip header
+
+	for {
+		row, err := r.Read()
+		if err != nil {
+			break
+		}
+		if len(row) < 3 {
+			continue
+		}
+		id, err := strconv.Atoi(row[1])
+		if err != nil {
+			continue
+		}
+		mv, ok := c.Movies[id]
+		if !ok {
+			continue
+		}
+		if tag := strings.ToLower(strings.TrimSpace(row[2])); tag != "" {
+			mv.Tags[tag]++
+		}
+	}
+	return nil
+}
+
+// Genres devuelve los géneros de una película (nil si no está en el catálogo)
+func (c *Catalog) Genres(id int) []string {
+	if mv, ok := c.Movies[id]; ok {
+		return mv.Genres
+	}
+	return nil
+}
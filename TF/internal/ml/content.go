@@ -0,0 +1,219 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// ----------------- Content-based -----------------
+
+// ContentBased: recomendador por contenido. Cada película es un vector TF-IDF de
+// géneros, tags y década de estreno; el perfil del usuario es el centroide de las
+// películas que calificó ponderado por rating centrado en su media (las que no le
+// gustaron restan). El coseno contra el perfil se lleva a un rating alrededor de
+// la media del usuario, así que también predice películas que nadie calificó todavía.
+// El perfil de cada usuario se arma la primera vez que se pide y queda cacheado
+// hasta el próximo Fit.
+type ContentBased struct {
+	Catalog *Catalog
+	Workers int // goroutines para puntuar candidatos (<= 1 -> secuencial)
+
+	ds         *Dataset
+	itemVecs   map[int]sparseVec
+	globalMean float64
+	profiles   sync.Map // user -> userProfile
+}
+
+// NewContentBased crea un recomendador por contenido sobre un catálogo
+func NewContentBased(cat *Catalog) *ContentBased {
+	return &ContentBased{Catalog: cat}
+}
+
+func (m *ContentBased) Name() string {
+	return "content(tfidf)"
+}
+
+// contentFeatures: término -> tf de una película. Los prefijos evitan que un tag
+// "drama" se confunda con el género Drama.
+func contentFeatures(mv *Movie) map[string]float64 {
+	tf := make(map[string]float64, len(mv.Genres)+len(mv.Tags)+1)
+	for _, g := range mv.Genres {
+		tf["g:"+g] = 1
+	}
+	for tag, n := range mv.Tags {
+		tf["t:"+tag] = 1 + math.Log(float64(n))
+	}
+	if mv.Year > 0 {
+		tf["d:"+strconv.Itoa(mv.Decade())] = 1
+	}
+	return tf
+}
+
+// Fit construye los vectores TF-IDF (normalizados L2) de todo el catálogo
+func (m *ContentBased) Fit(ds *Dataset) error {
+	if m.Catalog == nil {
+		return errors.New("ml: ContentBased necesita un catálogo")
+	}
+
+	// df por término
+	terms := make(map[int]map[string]float64, len(m.Catalog.Movies))
+	df := make(map[string]int)
+	for id, mv := range m.Catalog.Movies {
+		tf := contentFeatures(mv)
+		terms[id] = tf
+		for t := range tf {
+			df[t]++
+		}
+	}
+
+	// ids enteros para poder reutilizar los vectores dispersos
+	featureID := make(map[string]int, len(df))
+	for t := range df {
+		featureID[t] = len(featureID)
+	}
+
+	n := float64(len(m.Catalog.Movies))
+	vecs := make(map[int]sparseVec, len(terms))
+	for id, tf := range terms {
+		v := make(map[int]float64, len(tf))
+		norm := 0.0
+		for t, w := range tf {
+			x := w * math.Log(n/float64(df[t]))
+			if x == 0 {
+				continue
+			}
+			v[featureID[t]] = x
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		for k := range v {
+			v[k] /= norm
+		}
+		vecs[id] = toSparse(v)
+	}
+
+	sum, cnt := 0.0, 0
+	for _, ratings := range ds.UserRatings {
+		for _, r := range ratings {
+			sum += r
+			cnt++
+		}
+	}
+	m.globalMean = 0
+	if cnt > 0 {
+		m.globalMean = sum / float64(cnt)
+	}
+
+	m.ds = ds
+	m.itemVecs = vecs
+	m.profiles.Clear()
+	return nil
+}
+
+// userProfile: perfil de contenido del usuario, su media y la escala del
+// rating predicho
+type userProfile struct {
+	vec       sparseVec
+	mean, dev float64 // dev: desvío absoluto medio respecto de mean
+}
+
+// profile: centroide de los vectores de las películas calificadas, ponderado por
+// rating - media del usuario
+func (m *ContentBased) profile(ratings map[int]float64) userProfile {
+	up := userProfile{mean: meanOf(ratings)}
+	acc := make(map[int]float64)
+	total := 0.0
+	for it, r := range ratings {
+		w := r - up.mean
+		up.dev += math.Abs(w)
+		v, ok := m.itemVecs[it]
+		if !ok || w == 0 {
+			continue
+		}
+		for i, f := range v.ids {
+			acc[f] += w * v.vals[i]
+		}
+		total += math.Abs(w)
+	}
+	if len(ratings) > 0 {
+		up.dev /= float64(len(ratings))
+	}
+	if total == 0 {
+		return up
+	}
+	for f := range acc {
+		acc[f] /= total
+	}
+	up.vec = toSparse(acc)
+	return up
+}
+
+// userProfile: perfil del usuario del dataset de Fit (cacheado)
+func (m *ContentBased) userProfile(user int) userProfile {
+	if cached, ok := m.profiles.Load(user); ok {
+		return cached.(userProfile)
+	}
+	up := m.profile(m.ds.UserRatings[user])
+	m.profiles.Store(user, up)
+	return up
+}
+
+// predict: media + coseno(perfil, película) * desvío, en [0,1]
+func (up userProfile) predict(v sparseVec) float64 {
+	return clamp01(up.mean + cosineSparse(up.vec, v)*up.dev)
+}
+
+// Predict lleva el coseno (-1..1) entre el perfil y la película a un rating
+// alrededor de la media del usuario. Sin perfil devuelve la media del usuario
+// (o la global) con ok=false.
+func (m *ContentBased) Predict(user, item int) (float64, bool) {
+	if m.itemVecs == nil {
+		return 0, false
+	}
+	if len(m.ds.UserRatings[user]) == 0 {
+		return m.globalMean, false
+	}
+	up := m.userProfile(user)
+	v, ok := m.itemVecs[item]
+	if !ok || up.vec.norm == 0 {
+		return up.mean, false
+	}
+	return up.predict(v), true
+}
+
+func (m *ContentBased) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.itemVecs == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ratings, ok := opts.ratingsFor(m.ds, user)
+	if !ok {
+		return nil, nil
+	}
+	var up userProfile
+	if opts.Profile == nil {
+		up = m.userProfile(user)
+	} else {
+		up = m.profile(ratings) // perfil ad-hoc: no se cachea
+	}
+	if up.vec.norm == 0 {
+		return nil, nil
+	}
+
+	// candidatos = todo el catálogo menos lo ya visto (incluye películas sin ratings)
+	candidates := make([]int, 0, len(m.itemVecs))
+	for it := range m.itemVecs {
+		if opts.isCandidate(user, it, ratings) {
+			candidates = append(candidates, it)
+		}
+	}
+
+	return scoreCandidates(ctx, candidates, opts.TopK, m.Workers, func(item int) (float64, bool) {
+		return up.predict(m.itemVecs[item]), true
+	})
+}
@@ -0,0 +1,235 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// ----------------- Co-ocurrencia ("los que vieron esto también vieron") -----------------
+
+// AssocMetric: cómo puntuar la asociación A -> B
+type AssocMetric int
+
+const (
+	SupportAssoc    AssocMetric = iota // P(A,B) = n(A,B) / usuarios
+	ConfidenceAssoc                    // P(B|A) = n(A,B) / n(A)
+	LiftAssoc                          // P(A,B) / (P(A) P(B))
+	PMIAssoc                           // log2(lift)
+)
+
+// String devuelve el nombre corto de la métrica
+func (a AssocMetric) String() string {
+	switch a {
+	case SupportAssoc:
+		return "support"
+	case ConfidenceAssoc:
+		return "confidence"
+	case LiftAssoc:
+		return "lift"
+	case PMIAssoc:
+		return "pmi"
+	default:
+		return "unknown"
+	}
+}
+
+// CoOccurrence: modelo item-item por conteo de co-ocurrencias. "Ver" = haber
+// calificado la película, sin importar el rating. No necesita perfil de usuario:
+// SimilarItems sirve para la página de detalle de una película.
+type CoOccurrence struct {
+	Metric     AssocMetric
+	MinSupport int // mínimo de usuarios para un item y para un par (poda)
+	Workers    int // goroutines para contar (0 -> runtime.NumCPU())
+
+	ds      *Dataset
+	users   int
+	support map[int]int         // item -> usuarios que lo vieron
+	pairs   map[int]map[int]int // item -> (item -> usuarios que vieron ambos), simétrico
+}
+
+// NewCoOccurrence crea un modelo de co-ocurrencia sin entrenar
+func NewCoOccurrence(metric AssocMetric, minSupport int) *CoOccurrence {
+	return &CoOccurrence{Metric: metric, MinSupport: minSupport}
+}
+
+func (m *CoOccurrence) Name() string {
+	return fmt.Sprintf("co-occurrence(%s,minsup=%d)", m.Metric, m.MinSupport)
+}
+
+// Fit cuenta soportes y pares en paralelo. Los items con soporte < MinSupport se
+// descartan antes de contar pares (ningún par suyo puede superar el mínimo).
+func (m *CoOccurrence) Fit(ds *Dataset) error {
+	workers := m.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	support := make(map[int]int)
+	for _, ratings := range ds.UserRatings {
+		for it := range ratings {
+			support[it]++
+		}
+	}
+	for it, n := range support {
+		if n < m.MinSupport {
+			delete(support, it)
+		}
+	}
+
+	users := make(chan map[int]float64, workers*16)
+	out := make(chan map[int]map[int]int, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			partial := make(map[int]map[int]int) // sólo i < j
+			kept := make([]int, 0, 64)
+			for ratings := range users {
+				kept = kept[:0]
+				for it := range ratings {
+					if _, ok := support[it]; ok {
+						kept = append(kept, it)
+					}
+				}
+				for a := 0; a < len(kept); a++ {
+					for b := a + 1; b < len(kept); b++ {
+						i, j := kept[a], kept[b]
+						if i > j {
+							i, j = j, i
+						}
+						row, ok := partial[i]
+						if !ok {
+							row = make(map[int]int)
+							partial[i] = row
+						}
+						row[j]++
+					}
+				}
+			}
+			out <- partial
+		}()
+	}
+
+	go func() {
+		for _, ratings := range ds.UserRatings {
+			users <- ratings
+		}
+		close(users)
+		wg.Wait()
+		close(out)
+	}()
+
+	counts := make(map[int]map[int]int)
+	for partial := range out {
+		for i, row := range partial {
+			dst, ok := counts[i]
+			if !ok {
+				counts[i] = row
+				continue
+			}
+			for j, c := range row {
+				dst[j] += c
+			}
+		}
+	}
+
+	// podar pares por soporte y guardar ambas direcciones
+	pairs := make(map[int]map[int]int)
+	for i, row := range counts {
+		for j, c := range row {
+			if c < m.MinSupport {
+				continue
+			}
+			if pairs[i] == nil {
+				pairs[i] = make(map[int]int)
+			}
+			if pairs[j] == nil {
+				pairs[j] = make(map[int]int)
+			}
+			pairs[i][j] = c
+			pairs[j][i] = c
+		}
+	}
+
+	m.ds = ds
+	m.users = len(ds.UserRatings)
+	m.support = support
+	m.pairs = pairs
+	return nil
+}
+
+// assoc: puntuación de la regla a -> b con co-ocurrencia nab
+func (m *CoOccurrence) assoc(a, b, nab int) float64 {
+	na := float64(m.support[a])
+	nb := float64(m.support[b])
+	n := float64(m.users)
+	switch m.Metric {
+	case SupportAssoc:
+		return float64(nab) / n
+	case LiftAssoc:
+		return float64(nab) * n / (na * nb)
+	case PMIAssoc:
+		return math.Log2(float64(nab) * n / (na * nb))
+	default:
+		return float64(nab) / na
+	}
+}
+
+// SimilarItems: las k películas más asociadas a movieID (no depende del usuario)
+func (m *CoOccurrence) SimilarItems(movieID, k int) []ItemScore {
+	if m.pairs == nil {
+		return nil
+	}
+	scores := make(map[int]float64, len(m.pairs[movieID]))
+	for other, nab := range m.pairs[movieID] {
+		scores[other] = m.assoc(movieID, other, nab)
+	}
+	return topKFromMap(scores, k)
+}
+
+// Predict: el modelo no estima ratings, sólo asociaciones; devuelve la media del usuario
+func (m *CoOccurrence) Predict(user, item int) (float64, bool) {
+	if m.ds == nil {
+		return 0, false
+	}
+	return meanOf(m.ds.UserRatings[user]), false
+}
+
+// Recommend suma la asociación desde cada película vista por el usuario hacia
+// cada candidato no visto
+func (m *CoOccurrence) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.pairs == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ratings, ok := opts.ratingsFor(m.ds, user)
+	if !ok {
+		return nil, nil
+	}
+	scores := make(map[int]float64)
+	allowed := make(map[int]bool) // resultado del filtro por candidato, se evalúa una vez
+	for seen := range ratings {
+		if err := ctx.Err(); err != nil {
+			return topKFromMap(scores, opts.TopK), err
+		}
+		for other, nab := range m.pairs[seen] {
+			ok, checked := allowed[other]
+			if !checked {
+				ok = opts.isCandidate(user, other, ratings)
+				allowed[other] = ok
+			}
+			if !ok {
+				continue
+			}
+			scores[other] += m.assoc(seen, other, nab)
+		}
+	}
+	return topKFromMap(scores, opts.TopK), nil
+}
@@ -2,12 +2,16 @@ package ml
 
 import (
 	"encoding/csv"
+	"fmt"
+	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 )
 
 type Dataset struct {
 	UserRatings map[int]map[int]float64
+	Timestamps  map[int]map[int]int64 // user -> (movie -> unix), nil si el CSV no trae timestamp
 	Users       int
 	Movies      int
 }
@@ -40,22 +44,237 @@ func LoadDataset(path string) (*Dataset, error) {
 		mid, _ := strconv.Atoi(row[1])
 		raw, _ := strconv.ParseFloat(row[2], 64)
 
-		// normalización explícita 1..5 → [0..1]
-		rating := raw / 5.0
+		if len(row) >= 4 {
+			if ts, err := strconv.ParseInt(row[3], 10, 64); err == nil {
+				ds.AddRatingAt(uid, mid, NormalizeRating(raw), ts)
+				continue
+			}
+		}
+		ds.AddRating(uid, mid, NormalizeRating(raw))
+	}
+
+	return ds, nil
+}
+
+// NormalizeRating: normalización explícita 1..5 → [0..1]
+func NormalizeRating(raw float64) float64 {
+	return raw / 5.0
+}
+
+// AddRating agrega (o reemplaza) un rating ya normalizado y actualiza los contadores
+func (ds *Dataset) AddRating(user, movie int, rating float64) {
+	if _, ok := ds.UserRatings[user]; !ok {
+		ds.UserRatings[user] = map[int]float64{}
+	}
 
-		if _, ok := ds.UserRatings[uid]; !ok {
-			ds.UserRatings[uid] = map[int]float64{}
+	ds.UserRatings[user][movie] = rating
+
+	if user > ds.Users {
+		ds.Users = user
+	}
+	if movie > ds.Movies {
+		ds.Movies = movie
+	}
+}
+
+// AddRatingAt: AddRating registrando además el momento (unix) del rating
+func (ds *Dataset) AddRatingAt(user, movie int, rating float64, ts int64) {
+	ds.AddRating(user, movie, rating)
+	if ds.Timestamps == nil {
+		ds.Timestamps = make(map[int]map[int]int64)
+	}
+	if _, ok := ds.Timestamps[user]; !ok {
+		ds.Timestamps[user] = map[int]int64{}
+	}
+	ds.Timestamps[user][movie] = ts
+}
+
+// Timestamp: momento del rating (0 si no se conoce)
+func (ds *Dataset) Timestamp(user, movie int) int64 {
+	return ds.Timestamps[user][movie]
+}
+
+// MaxTimestamp: el rating más reciente del dataset (0 sin timestamps)
+func (ds *Dataset) MaxTimestamp() int64 {
+	var latest int64
+	for _, items := range ds.Timestamps {
+		for _, ts := range items {
+			latest = max(latest, ts)
 		}
+	}
+	return latest
+}
+
+// copyRating: copia un rating (y su timestamp, si lo hay) de src a ds
+func (ds *Dataset) copyRating(src *Dataset, user, movie int) {
+	if ts, ok := src.Timestamps[user][movie]; ok {
+		ds.AddRatingAt(user, movie, src.UserRatings[user][movie], ts)
+		return
+	}
+	ds.AddRating(user, movie, src.UserRatings[user][movie])
+}
 
-		ds.UserRatings[uid][mid] = rating
+// NumRatings: total de ratings del dataset
+func (ds *Dataset) NumRatings() int {
+	n := 0
+	for _, items := range ds.UserRatings {
+		n += len(items)
+	}
+	return n
+}
 
-		if uid > ds.Users {
-			ds.Users = uid
+// Clone: copia independiente del dataset (ratings y timestamps)
+func (ds *Dataset) Clone() *Dataset {
+	out := &Dataset{UserRatings: make(map[int]map[int]float64, len(ds.UserRatings))}
+	for u, items := range ds.UserRatings {
+		for it := range items {
+			out.copyRating(ds, u, it)
 		}
-		if mid > ds.Movies {
-			ds.Movies = mid
+	}
+	return out
+}
+
+// sortedUsers: ids de usuario en orden ascendente (para recorridos reproducibles)
+func (ds *Dataset) sortedUsers() []int {
+	users := make([]int, 0, len(ds.UserRatings))
+	for u := range ds.UserRatings {
+		users = append(users, u)
+	}
+	sort.Ints(users)
+	return users
+}
+
+// sortedItems: ids de película con algún rating, en orden ascendente
+func sortedItems(ds *Dataset) []int {
+	set := make(map[int]struct{})
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			set[it] = struct{}{}
 		}
 	}
+	out := make([]int, 0, len(set))
+	for it := range set {
+		out = append(out, it)
+	}
+	sort.Ints(out)
+	return out
+}
 
-	return ds, nil
+// Split: separa al azar (con semilla) una fracción de los ratings de cada usuario
+// como test. Cada usuario conserva al menos un rating en train. El mismo seed
+// produce siempre la misma partición.
+func (ds *Dataset) Split(testFraction float64, seed int64) (train, test *Dataset) {
+	rng := rand.New(rand.NewSource(seed))
+	train = &Dataset{UserRatings: make(map[int]map[int]float64)}
+	test = &Dataset{UserRatings: make(map[int]map[int]float64)}
+
+	for _, u := range ds.sortedUsers() {
+		items := make([]int, 0, len(ds.UserRatings[u]))
+		for it := range ds.UserRatings[u] {
+			items = append(items, it)
+		}
+		sort.Ints(items)
+		rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+
+		nTest := int(float64(len(items)) * testFraction)
+		if nTest >= len(items) {
+			nTest = len(items) - 1
+		}
+		for i, it := range items {
+			if i < nTest {
+				test.copyRating(ds, u, it)
+			} else {
+				train.copyRating(ds, u, it)
+			}
+		}
+	}
+	return train, test
+}
+
+// Fold: una partición train/test de KFold
+type Fold struct {
+	Train, Test *Dataset
+}
+
+// KFold: reparte (con semilla) los ratings de cada usuario en k grupos; el fold i
+// usa el grupo i como test y el resto como train. Cada rating está en el test de
+// exactamente un fold. Los usuarios con menos de k ratings no aparecen en el test
+// de todos los folds. k tiene que ser al menos 2 (con 1 el train queda vacío).
+func (ds *Dataset) KFold(k int, seed int64) ([]Fold, error) {
+	if k < 2 {
+		return nil, fmt.Errorf("ml: k-fold necesita k >= 2 (k=%d)", k)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	folds := make([]Fold, k)
+	for i := range folds {
+		folds[i] = Fold{
+			Train: &Dataset{UserRatings: make(map[int]map[int]float64)},
+			Test:  &Dataset{UserRatings: make(map[int]map[int]float64)},
+		}
+	}
+
+	for _, u := range ds.sortedUsers() {
+		items := make([]int, 0, len(ds.UserRatings[u]))
+		for it := range ds.UserRatings[u] {
+			items = append(items, it)
+		}
+		sort.Ints(items)
+		rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+		// fold inicial al azar para que los usuarios con pocos ratings no caigan todos en el fold 0
+		offset := rng.Intn(k)
+
+		for n, it := range items {
+			for i := range folds {
+				if (n+offset)%k == i {
+					folds[i].Test.copyRating(ds, u, it)
+				} else {
+					folds[i].Train.copyRating(ds, u, it)
+				}
+			}
+		}
+	}
+	return folds, nil
+}
+
+// Sequence: películas del usuario en el orden en que las calificó (timestamp,
+// desempate por id). Sin timestamps devuelve nil.
+func (ds *Dataset) Sequence(user int) []int {
+	times := ds.Timestamps[user]
+	if len(times) == 0 {
+		return nil
+	}
+	items := make([]int, 0, len(times))
+	for it := range times {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if times[items[i]] != times[items[j]] {
+			return times[items[i]] < times[items[j]]
+		}
+		return items[i] < items[j]
+	})
+	return items
+}
+
+// LeaveLastOut: para cada usuario con al menos 2 ratings con timestamp, el último
+// que calificó va a test y el resto a train (los usuarios sin historia
+// suficiente quedan enteros en train).
+func (ds *Dataset) LeaveLastOut() (train, test *Dataset) {
+	train = &Dataset{UserRatings: make(map[int]map[int]float64)}
+	test = &Dataset{UserRatings: make(map[int]map[int]float64)}
+
+	for _, u := range ds.sortedUsers() {
+		seq := ds.Sequence(u)
+		last := -1
+		if len(seq) >= 2 {
+			last = seq[len(seq)-1]
+			test.copyRating(ds, u, last)
+		}
+		for it := range ds.UserRatings[u] {
+			if it != last {
+				train.copyRating(ds, u, it)
+			}
+		}
+	}
+	return train, test
 }
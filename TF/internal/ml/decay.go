@@ -0,0 +1,87 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ----------------- Decaimiento temporal -----------------
+
+// DecayMode: forma del decaimiento
+type DecayMode int
+
+const (
+	ExponentialDecay DecayMode = iota // peso = 0.5^(edad/HalfLife)
+	WindowDecay                       // peso = 1 dentro de Window, OldWeight fuera
+)
+
+// TimeDecay pondera cada rating según su antigüedad respecto a Ref. Se aplica a
+// las similitudes (Cosine/Pearson/Jaccard) y a los pesos de la predicción de los
+// modelos KNN. Los ratings sin timestamp pesan 1.
+type TimeDecay struct {
+	Mode      DecayMode
+	HalfLife  time.Duration // ExponentialDecay
+	Window    time.Duration // WindowDecay
+	OldWeight float64       // WindowDecay: peso de los ratings fuera de la ventana
+	Ref       int64         // instante de referencia (unix); 0 = rating más reciente del dataset
+}
+
+// NewExponentialDecay: decaimiento exponencial con la vida media indicada
+func NewExponentialDecay(halfLife time.Duration) *TimeDecay {
+	return &TimeDecay{Mode: ExponentialDecay, HalfLife: halfLife}
+}
+
+// NewWindowDecay: sólo cuentan los ratings de la última ventana
+func NewWindowDecay(window time.Duration) *TimeDecay {
+	return &TimeDecay{Mode: WindowDecay, Window: window}
+}
+
+func (d *TimeDecay) String() string {
+	if d.Mode == WindowDecay {
+		return fmt.Sprintf("window(%s)", days(d.Window))
+	}
+	return fmt.Sprintf("exp(hl=%s)", days(d.HalfLife))
+}
+
+// decaySuffix: sufijo para Name() de los modelos ("" sin decay)
+func decaySuffix(d *TimeDecay) string {
+	if d == nil {
+		return ""
+	}
+	return ",decay=" + d.String()
+}
+
+func days(d time.Duration) string {
+	return fmt.Sprintf("%gd", math.Round(d.Hours()/24*10)/10)
+}
+
+// resolve devuelve una copia con Ref fijado (si venía en 0) al rating más reciente de ds
+func (d *TimeDecay) resolve(ds *Dataset) *TimeDecay {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	if c.Ref == 0 {
+		c.Ref = ds.MaxTimestamp()
+	}
+	return &c
+}
+
+// Weight: peso de un rating hecho en ts (unix); 1 si ts == 0 (desconocido)
+func (d *TimeDecay) Weight(ts int64) float64 {
+	if d == nil || ts == 0 {
+		return 1
+	}
+	age := time.Duration(max(d.Ref-ts, 0)) * time.Second
+	if d.Mode == WindowDecay {
+		if age <= d.Window {
+			return 1
+		}
+		return d.OldWeight
+	}
+	if d.HalfLife <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(d.HalfLife))
+}
@@ -0,0 +1,77 @@
+package ml
+
+import (
+	"sort"
+)
+
+// ----------------- Explicaciones -----------------
+
+// Tipos de vecino de una explicación
+const (
+	ExplainItems = "item" // item-based: películas que el usuario calificó
+	ExplainUsers = "user" // user-based: usuarios vecinos que calificaron la película
+)
+
+// defaultExplainTop: contribuciones por recomendación si no se indica otra cosa
+const defaultExplainTop = 5
+
+// Contribution: un vecino que aportó a la predicción
+type Contribution struct {
+	ID         int     `json:"id"`              // movieId (item) o userId (user)
+	Similarity float64 `json:"similarity"`      // similitud con la película / el usuario objetivo
+	Rating     float64 `json:"rating"`          // rating del vecino (normalizado 0..1)
+	Decay      float64 `json:"decay,omitempty"` // peso temporal del rating (0 = sin decaimiento)
+	Weight     float64 `json:"weight"`          // |sim|*decay / sum |sim|*decay de todos los vecinos usados
+}
+
+// Explanation: por qué se recomendó una película
+type Explanation struct {
+	Kind          string         `json:"kind"`
+	Metric        string         `json:"metric"`
+	NeighborK     int            `json:"neighbor_k"` // 0 = todos
+	Neighbors     int            `json:"neighbors"`  // vecinos que realmente aportaron
+	Contributions []Contribution `json:"contributions"`
+}
+
+// newExplanation calcula los pesos de todos los vecinos y se queda con los top
+// de mayor peso
+func newExplanation(kind string, metric SimMetric, neighborK int, contribs []Contribution, top int) *Explanation {
+	if top <= 0 {
+		top = defaultExplainTop
+	}
+	raw := func(c Contribution) float64 {
+		if c.Decay == 0 {
+			return abs(c.Similarity)
+		}
+		return abs(c.Similarity) * c.Decay
+	}
+	den := 0.0
+	for _, c := range contribs {
+		den += raw(c)
+	}
+	for i := range contribs {
+		if den > 0 {
+			contribs[i].Weight = raw(contribs[i]) / den
+		}
+	}
+	sort.Slice(contribs, func(i, j int) bool {
+		if contribs[i].Weight != contribs[j].Weight {
+			return contribs[i].Weight > contribs[j].Weight
+		}
+		return contribs[i].ID < contribs[j].ID
+	})
+	return &Explanation{
+		Kind:          kind,
+		Metric:        metric.String(),
+		NeighborK:     neighborK,
+		Neighbors:     len(contribs),
+		Contributions: truncateContributions(contribs, top),
+	}
+}
+
+func truncateContributions(c []Contribution, k int) []Contribution {
+	if len(c) > k {
+		return c[:k]
+	}
+	return c
+}
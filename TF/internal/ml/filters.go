@@ -0,0 +1,159 @@
+package ml
+
+import (
+	"sync"
+)
+
+// ----------------- Filtros de candidatos -----------------
+
+// Filter decide si una película puede ser candidata para un usuario. Los
+// recomendadores lo aplican antes de puntuar (RecommendOptions.Filter), así los
+// items excluidos no cuestan ningún cálculo de similitud.
+type Filter interface {
+	Allow(user, item int) bool
+}
+
+// FilterFunc adapta una función a Filter
+type FilterFunc func(user, item int) bool
+
+func (f FilterFunc) Allow(user, item int) bool { return f(user, item) }
+
+// AllOf: pasa si pasan todos los filtros (los nil se ignoran)
+func AllOf(filters ...Filter) Filter {
+	return FilterFunc(func(user, item int) bool {
+		for _, f := range filters {
+			if f != nil && !f.Allow(user, item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// AnyOf: pasa si pasa al menos uno de los filtros
+func AnyOf(filters ...Filter) Filter {
+	return FilterFunc(func(user, item int) bool {
+		for _, f := range filters {
+			if f != nil && f.Allow(user, item) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not invierte un filtro
+func Not(f Filter) Filter {
+	return FilterFunc(func(user, item int) bool { return !f.Allow(user, item) })
+}
+
+// IncludeGenres: películas con al menos uno de los géneros
+func IncludeGenres(cat *Catalog, genres ...string) Filter {
+	set := stringSet(genres)
+	return FilterFunc(func(_, item int) bool {
+		for _, g := range cat.Genres(item) {
+			if _, ok := set[g]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExcludeGenres: películas sin ninguno de los géneros
+func ExcludeGenres(cat *Catalog, genres ...string) Filter {
+	return Not(IncludeGenres(cat, genres...))
+}
+
+// YearRange: estrenadas entre from y to inclusive (0 = sin límite). Las
+// películas sin año conocido no pasan si hay algún límite.
+func YearRange(cat *Catalog, from, to int) Filter {
+	return FilterFunc(func(_, item int) bool {
+		mv, ok := cat.Movies[item]
+		if !ok || mv.Year == 0 {
+			return from == 0 && to == 0
+		}
+		return (from == 0 || mv.Year >= from) && (to == 0 || mv.Year <= to)
+	})
+}
+
+// AllowList: sólo estas películas
+func AllowList(ids ...int) Filter {
+	set := intSet(ids)
+	return FilterFunc(func(_, item int) bool {
+		_, ok := set[item]
+		return ok
+	})
+}
+
+// DenyList: todas menos estas películas
+func DenyList(ids ...int) Filter {
+	set := intSet(ids)
+	return FilterFunc(func(_, item int) bool {
+		_, ok := set[item]
+		return !ok
+	})
+}
+
+// MinPopularity: películas con al menos minRatings ratings en ds
+func MinPopularity(ds *Dataset, minRatings int) Filter {
+	counts := make(map[int]int)
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			counts[it]++
+		}
+	}
+	return FilterFunc(func(_, item int) bool { return counts[item] >= minRatings })
+}
+
+// NotInterested: películas que cada usuario marcó como "no me interesa".
+// Es seguro marcar mientras se recomienda.
+type NotInterested struct {
+	mu     sync.RWMutex
+	byUser map[int]map[int]struct{}
+}
+
+// NewNotInterested crea una lista vacía
+func NewNotInterested() *NotInterested {
+	return &NotInterested{byUser: make(map[int]map[int]struct{})}
+}
+
+// Mark registra que a user no le interesa item
+func (n *NotInterested) Mark(user, item int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.byUser[user] == nil {
+		n.byUser[user] = make(map[int]struct{})
+	}
+	n.byUser[user][item] = struct{}{}
+}
+
+// Unmark deshace Mark
+func (n *NotInterested) Unmark(user, item int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.byUser[user], item)
+}
+
+func (n *NotInterested) Allow(user, item int) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, marked := n.byUser[user][item]
+	return !marked
+}
+
+func intSet(ids []int) map[int]struct{} {
+	set := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func stringSet(xs []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(xs))
+	for _, x := range xs {
+		set[x] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,172 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// ----------------- Recomendaciones para grupos -----------------
+
+// GroupStrategy: cómo agregar las predicciones de los miembros
+type GroupStrategy int
+
+const (
+	AverageStrategy              GroupStrategy = iota // media de los miembros
+	LeastMiseryStrategy                               // el miembro menos contento
+	MostPleasureStrategy                              // el miembro más contento
+	AverageWithoutMiseryStrategy                      // media, descartando items con algún miembro bajo el umbral
+)
+
+// String devuelve el nombre corto de la estrategia
+func (s GroupStrategy) String() string {
+	switch s {
+	case AverageStrategy:
+		return "average"
+	case LeastMiseryStrategy:
+		return "least-misery"
+	case MostPleasureStrategy:
+		return "most-pleasure"
+	case AverageWithoutMiseryStrategy:
+		return "average-without-misery"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultMiseryThreshold: 2.5 estrellas en escala normalizada
+const DefaultMiseryThreshold = 0.5
+
+// GroupOptions: parámetros de GroupRecommend
+type GroupOptions struct {
+	TopK            int
+	Strategy        GroupStrategy
+	MiseryThreshold float64 // AverageWithoutMisery, normalizado 0..1 (<= 0 -> DefaultMiseryThreshold)
+	PoolFactor      int     // candidatos por miembro = TopK*PoolFactor (0 -> 5)
+	Filter          Filter  // filtro adicional de candidatos
+}
+
+// GroupItemScore: score agregado y predicción de cada miembro
+type GroupItemScore struct {
+	MovieID int             `json:"movie_id"`
+	Score   float64         `json:"score"`
+	Members map[int]float64 `json:"members"` // userId -> predicción (normalizada 0..1)
+}
+
+// GroupRecommend arma una lista para varios usuarios a la vez sobre cualquier
+// Recommender ya entrenado con ds. Los candidatos son la unión de las listas
+// individuales de cada miembro, sin nada que algún miembro ya haya visto; cada
+// candidato se predice para todos los miembros y se agrega según la estrategia.
+func GroupRecommend(ctx context.Context, rec Recommender, ds *Dataset, members []int, opts GroupOptions) ([]GroupItemScore, error) {
+	if len(members) == 0 {
+		return nil, errors.New("ml: grupo vacío")
+	}
+	threshold := opts.MiseryThreshold
+	if threshold <= 0 {
+		threshold = DefaultMiseryThreshold
+	}
+	f := opts.PoolFactor
+	if f <= 0 {
+		f = defaultPoolFactor
+	}
+
+	seenByGroup := FilterFunc(func(_, item int) bool {
+		for _, u := range members {
+			if _, ok := ds.UserRatings[u][item]; ok {
+				return false
+			}
+		}
+		return true
+	})
+	pool := RecommendOptions{TopK: opts.TopK * f, Filter: AllOf(opts.Filter, seenByGroup)}
+
+	// candidatos: listas individuales en paralelo
+	lists := make([][]ItemScore, len(members))
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, u := range members {
+		wg.Add(1)
+		go func(i, u int) {
+			defer wg.Done()
+			lists[i], errs[i] = rec.Recommend(ctx, u, pool)
+		}(i, u)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]int, 0)
+	added := make(map[int]bool)
+	for _, list := range lists {
+		for _, it := range list {
+			if !added[it.MovieID] {
+				added[it.MovieID] = true
+				candidates = append(candidates, it.MovieID)
+			}
+		}
+	}
+
+	// predicción de cada miembro para cada candidato (baseline si el modelo no puede)
+	preds := make([][]float64, len(members))
+	for i, u := range members {
+		wg.Add(1)
+		go func(i, u int) {
+			defer wg.Done()
+			preds[i] = make([]float64, len(candidates))
+			for c, it := range candidates {
+				if ctx.Err() != nil {
+					return
+				}
+				preds[i][c], _ = rec.Predict(u, it)
+			}
+		}(i, u)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]GroupItemScore, 0, len(candidates))
+	for c, it := range candidates {
+		g := GroupItemScore{MovieID: it, Members: make(map[int]float64, len(members))}
+		sum, lo, hi := 0.0, math.Inf(1), math.Inf(-1)
+		for i, u := range members {
+			p := preds[i][c]
+			g.Members[u] = p
+			sum += p
+			lo = min(lo, p)
+			hi = max(hi, p)
+		}
+		switch opts.Strategy {
+		case LeastMiseryStrategy:
+			g.Score = lo
+		case MostPleasureStrategy:
+			g.Score = hi
+		case AverageWithoutMiseryStrategy:
+			if lo < threshold {
+				continue
+			}
+			g.Score = sum / float64(len(members))
+		case AverageStrategy:
+			g.Score = sum / float64(len(members))
+		default:
+			return nil, fmt.Errorf("ml: estrategia de grupo desconocida %d", opts.Strategy)
+		}
+		out = append(out, g)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].MovieID < out[j].MovieID
+	})
+	if len(out) > opts.TopK {
+		out = out[:opts.TopK]
+	}
+	return out, nil
+}
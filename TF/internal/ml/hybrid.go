@@ -0,0 +1,468 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ----------------- Híbrido -----------------
+
+// HybridMode: cómo combinar los recomendadores
+type HybridMode int
+
+const (
+	// WeightedHybrid: suma ponderada de scores normalizados (min-max) por modelo
+	WeightedHybrid HybridMode = iota
+	// SwitchingHybrid: elige un modelo por usuario según Rules (default Members[0])
+	SwitchingHybrid
+	// CascadeHybrid: Members[0] genera candidatos y cada modelo siguiente los
+	// re-puntúa con Predict y recorta la lista, hasta quedar TopK
+	CascadeHybrid
+)
+
+// HybridMember: un recomendador y sus pesos (sólo usados en WeightedHybrid).
+// Weight pondera ratings en Predict y RankWeight scores normalizados en
+// Recommend; son escalas distintas y se aprenden por separado (LearnWeights y
+// LearnRankWeights). Si ningún miembro tiene RankWeight, Recommend usa Weight.
+type HybridMember struct {
+	Rec        Recommender
+	Weight     float64
+	RankWeight float64
+}
+
+// SwitchRule: los usuarios con menos de MaxRatings ratings usan Rec
+type SwitchRule struct {
+	MaxRatings int
+	Rec        Recommender
+}
+
+// defaultPoolFactor: cuántas veces TopK se le pide a cada modelo antes de combinar
+const defaultPoolFactor = 5
+
+// Hybrid combina varios Recommender y es a su vez un Recommender
+type Hybrid struct {
+	Mode       HybridMode
+	Members    []HybridMember
+	Rules      []SwitchRule // reglas de SwitchingHybrid, se evalúan en orden
+	PoolFactor int          // candidatos por modelo = TopK*PoolFactor (0 -> 5)
+
+	ds *Dataset
+}
+
+// NewHybrid crea un híbrido con pesos iguales
+func NewHybrid(mode HybridMode, recs ...Recommender) *Hybrid {
+	h := &Hybrid{Mode: mode}
+	for _, r := range recs {
+		w := 1 / float64(len(recs))
+		h.Members = append(h.Members, HybridMember{Rec: r, Weight: w, RankWeight: w})
+	}
+	return h
+}
+
+func (h *Hybrid) Name() string {
+	parts := make([]string, len(h.Members))
+	for i, mb := range h.Members {
+		if h.Mode == WeightedHybrid {
+			parts[i] = fmt.Sprintf("%s*%.2f", mb.Rec.Name(), mb.Weight)
+		} else {
+			parts[i] = mb.Rec.Name()
+		}
+	}
+	switch h.Mode {
+	case SwitchingHybrid:
+		rules := make([]string, len(h.Rules))
+		for i, r := range h.Rules {
+			rules[i] = fmt.Sprintf("<%d:%s", r.MaxRatings, r.Rec.Name())
+		}
+		return fmt.Sprintf("hybrid-switching(%s|default:%s)", strings.Join(rules, ","), strings.Join(parts, ","))
+	case CascadeHybrid:
+		return "hybrid-cascade(" + strings.Join(parts, ">") + ")"
+	default:
+		return "hybrid-weighted(" + strings.Join(parts, "+") + ")"
+	}
+}
+
+func (h *Hybrid) poolSize(topK int) int {
+	f := h.PoolFactor
+	if f <= 0 {
+		f = defaultPoolFactor
+	}
+	return topK * f
+}
+
+// Fit entrena todos los modelos (miembros y reglas); cada modelo se entrena una vez
+func (h *Hybrid) Fit(ds *Dataset) error {
+	if len(h.Members) == 0 {
+		return errors.New("ml: Hybrid sin modelos")
+	}
+	fitted := make(map[Recommender]bool)
+	fit := func(r Recommender) error {
+		if fitted[r] {
+			return nil
+		}
+		fitted[r] = true
+		if err := r.Fit(ds); err != nil {
+			return fmt.Errorf("%s: %w", r.Name(), err)
+		}
+		return nil
+	}
+	for _, mb := range h.Members {
+		if err := fit(mb.Rec); err != nil {
+			return err
+		}
+	}
+	for _, rule := range h.Rules {
+		if err := fit(rule.Rec); err != nil {
+			return err
+		}
+	}
+	h.ds = ds
+	return nil
+}
+
+// pick: modelo que atiende a user en SwitchingHybrid (con perfil ad-hoc cuenta
+// los ratings del perfil)
+func (h *Hybrid) pick(user int, opts RecommendOptions) Recommender {
+	ratings, _ := opts.ratingsFor(h.ds, user)
+	n := len(ratings)
+	for _, rule := range h.Rules {
+		if n < rule.MaxRatings {
+			return rule.Rec
+		}
+	}
+	return h.Members[0].Rec
+}
+
+func (h *Hybrid) Predict(user, item int) (float64, bool) {
+	if h.ds == nil {
+		return 0, false
+	}
+	switch h.Mode {
+	case SwitchingHybrid:
+		return h.pick(user, RecommendOptions{}).Predict(user, item)
+	case CascadeHybrid:
+		return h.Members[len(h.Members)-1].Rec.Predict(user, item)
+	}
+	w := make([]float64, len(h.Members))
+	preds := make([]float64, len(h.Members))
+	ok := make([]bool, len(h.Members))
+	any := false
+	for m, mb := range h.Members {
+		w[m] = mb.Weight
+		preds[m], ok[m] = mb.Rec.Predict(user, item)
+		any = any || (ok[m] && mb.Weight > 0)
+	}
+	return blend(w, preds, ok), any
+}
+
+func (h *Hybrid) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if h.ds == nil {
+		return nil, ErrNotFitted
+	}
+	switch h.Mode {
+	case SwitchingHybrid:
+		return h.pick(user, opts).Recommend(ctx, user, opts)
+	case CascadeHybrid:
+		return h.cascade(ctx, user, opts)
+	default:
+		return h.weighted(ctx, user, opts)
+	}
+}
+
+// weighted: cada modelo devuelve TopK*PoolFactor candidatos (en paralelo), sus
+// scores se normalizan a [0,1] y se suman ponderados
+func (h *Hybrid) weighted(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	pool := opts
+	pool.TopK = h.poolSize(opts.TopK)
+
+	lists := make([][]ItemScore, len(h.Members))
+	errs := make([]error, len(h.Members))
+	var wg sync.WaitGroup
+	for i, mb := range h.Members {
+		wg.Add(1)
+		go func(i int, rec Recommender) {
+			defer wg.Done()
+			lists[i], errs[i] = rec.Recommend(ctx, user, pool)
+		}(i, mb.Rec)
+	}
+	wg.Wait()
+
+	w := h.rankWeights()
+	scores := make(map[int]float64)
+	for i, list := range lists {
+		for id, sc := range normalizeScores(list) {
+			scores[id] += w[i] * sc
+		}
+	}
+	return topKFromMap(scores, opts.TopK), errors.Join(errs...)
+}
+
+// rankWeights: pesos de Recommend (RankWeight, o Weight si ninguno está fijado)
+func (h *Hybrid) rankWeights() []float64 {
+	w := make([]float64, len(h.Members))
+	set := false
+	for m, mb := range h.Members {
+		w[m] = mb.RankWeight
+		set = set || mb.RankWeight > 0
+	}
+	if !set {
+		for m, mb := range h.Members {
+			w[m] = mb.Weight
+		}
+	}
+	return w
+}
+
+// cascade: el primer modelo propone TopK*PoolFactor candidatos; cada etapa
+// siguiente los re-puntúa con su Predict y se queda con una lista más corta
+func (h *Hybrid) cascade(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	pool := opts
+	pool.TopK = h.poolSize(opts.TopK)
+	cands, err := h.Members[0].Rec.Recommend(ctx, user, pool)
+	if err != nil {
+		return cands, err
+	}
+
+	stages := len(h.Members) - 1
+	for s := 1; s <= stages; s++ {
+		if err := ctx.Err(); err != nil {
+			return truncate(cands, opts.TopK), err
+		}
+		// tamaño decrece linealmente de pool.TopK a opts.TopK
+		keep := pool.TopK - (pool.TopK-opts.TopK)*s/stages
+		rec := h.Members[s].Rec
+		scores := make(map[int]float64, len(cands))
+		for _, c := range cands {
+			scores[c.MovieID], _ = rec.Predict(user, c.MovieID)
+		}
+		cands = topKFromMap(scores, keep)
+	}
+	return truncate(cands, opts.TopK), nil
+}
+
+// LearnWeights ajusta los Weight de WeightedHybrid (los de Predict) minimizando
+// el RMSE de Predict sobre validation (los miembros ya deben estar entrenados
+// sobre la parte de train): la misma mezcla que Predict, renormalizada sobre los
+// miembros que pudieron predecir cada par. Recorre el simplex de pesos con paso
+// step (p.ej. 0.1) y devuelve el RMSE del mejor juego de pesos.
+func (h *Hybrid) LearnWeights(ctx context.Context, validation *Dataset, step float64) (float64, error) {
+	if h.ds == nil {
+		return 0, ErrNotFitted
+	}
+	if step <= 0 || step > 1 {
+		return 0, errors.New("ml: step debe estar en (0,1]")
+	}
+
+	// predicciones de cada miembro para cada par de validación, en paralelo por usuario
+	type sample struct {
+		preds  []float64
+		ok     []bool
+		rating float64
+	}
+	users := validation.sortedUsers()
+	perUser := make([][]sample, len(users))
+	forEachIndex(ctx, len(users), func(i int) {
+		u := users[i]
+		for it, r := range validation.UserRatings[u] {
+			s := sample{preds: make([]float64, len(h.Members)), ok: make([]bool, len(h.Members)), rating: r}
+			for m, mb := range h.Members {
+				s.preds[m], s.ok[m] = mb.Rec.Predict(u, it)
+			}
+			perUser[i] = append(perUser[i], s)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var samples []sample
+	for _, s := range perUser {
+		samples = append(samples, s...)
+	}
+	if len(samples) == 0 {
+		return 0, errors.New("ml: validación vacía")
+	}
+
+	steps := int(math.Round(1 / step))
+	best := math.Inf(1)
+	var bestW []float64
+	forEachSimplexPoint(len(h.Members), steps, func(w []float64) {
+		se := 0.0
+		for _, s := range samples {
+			p := blend(w, s.preds, s.ok)
+			se += (p - s.rating) * (p - s.rating)
+		}
+		if rmse := math.Sqrt(se / float64(len(samples))); rmse < best {
+			best = rmse
+			bestW = append(bestW[:0], w...)
+		}
+	})
+	for m := range h.Members {
+		h.Members[m].Weight = bestW[m]
+	}
+	return best, nil
+}
+
+// LearnRankWeights ajusta los RankWeight de WeightedHybrid (los de Recommend)
+// maximizando el NDCG@k sobre validation de la misma mezcla que Recommend: los
+// TopK*PoolFactor candidatos de cada miembro con scores normalizados min-max.
+// Son relevantes los ratings de validation >= threshold (normalizado). Los
+// miembros ya deben estar entrenados sobre la parte de train. Recorre el simplex
+// con paso step y devuelve el NDCG@k del mejor juego de pesos.
+func (h *Hybrid) LearnRankWeights(ctx context.Context, validation *Dataset, k int, threshold, step float64) (float64, error) {
+	if h.ds == nil {
+		return 0, ErrNotFitted
+	}
+	if step <= 0 || step > 1 {
+		return 0, errors.New("ml: step debe estar en (0,1]")
+	}
+	if k <= 0 {
+		return 0, errors.New("ml: k debe ser positivo")
+	}
+
+	// scores normalizados de cada miembro para cada usuario con algún relevante
+	type sample struct {
+		lists    []map[int]float64
+		relevant map[int]bool
+	}
+	users := validation.sortedUsers()
+	perUser := make([]*sample, len(users))
+	errs := make([]error, len(users))
+	forEachIndex(ctx, len(users), func(i int) {
+		u := users[i]
+		s := &sample{relevant: make(map[int]bool)}
+		for it, r := range validation.UserRatings[u] {
+			if r >= threshold {
+				s.relevant[it] = true
+			}
+		}
+		if len(s.relevant) == 0 {
+			return
+		}
+		for _, mb := range h.Members {
+			list, err := mb.Rec.Recommend(ctx, u, RecommendOptions{TopK: h.poolSize(k)})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			s.lists = append(s.lists, normalizeScores(list))
+		}
+		perUser[i] = s
+	})
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+	var samples []*sample
+	for _, s := range perUser {
+		if s != nil {
+			samples = append(samples, s)
+		}
+	}
+	if len(samples) == 0 {
+		return 0, errors.New("ml: validación sin items relevantes")
+	}
+
+	steps := int(math.Round(1 / step))
+	best := math.Inf(-1)
+	var bestW []float64
+	scores := make(map[int]float64)
+	forEachSimplexPoint(len(h.Members), steps, func(w []float64) {
+		total := 0.0
+		for _, s := range samples {
+			clear(scores)
+			for m, list := range s.lists {
+				for id, sc := range list {
+					scores[id] += w[m] * sc
+				}
+			}
+			dcg, idcg := 0.0, 0.0
+			for pos, it := range topKFromMap(scores, k) {
+				if s.relevant[it.MovieID] {
+					dcg += 1 / math.Log2(float64(pos+2))
+				}
+			}
+			for pos := 0; pos < min(k, len(s.relevant)); pos++ {
+				idcg += 1 / math.Log2(float64(pos+2))
+			}
+			total += dcg / idcg
+		}
+		if ndcg := total / float64(len(samples)); ndcg > best {
+			best = ndcg
+			bestW = append(bestW[:0], w...)
+		}
+	})
+	for m := range h.Members {
+		h.Members[m].RankWeight = bestW[m]
+	}
+	return best, nil
+}
+
+// forEachIndex llama fn(i) para i en [0,n) con runtime.NumCPU() goroutines;
+// deja de repartir trabajo en cuanto se cancela ctx
+func forEachIndex(ctx context.Context, n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// blend: la combinación de Predict para un par: media ponderada de los miembros
+// con ok, o la predicción (baseline) del último que no pudo si ninguno pudo
+func blend(w, preds []float64, ok []bool) float64 {
+	num, den := 0.0, 0.0
+	fallback, any := 0.0, false
+	for m := range w {
+		if !ok[m] {
+			fallback = preds[m]
+			continue
+		}
+		num += w[m] * preds[m]
+		den += w[m]
+		any = true
+	}
+	if !any || den == 0 {
+		return fallback
+	}
+	return num / den
+}
+
+// forEachSimplexPoint llama fn con cada vector de n pesos múltiplos de 1/steps que suman 1
+func forEachSimplexPoint(n, steps int, fn func(w []float64)) {
+	w := make([]float64, n)
+	var rec func(i, left int)
+	rec = func(i, left int) {
+		if i == n-1 {
+			w[i] = float64(left) / float64(steps)
+			fn(w)
+			return
+		}
+		for k := 0; k <= left; k++ {
+			w[i] = float64(k) / float64(steps)
+			rec(i+1, left-k)
+		}
+	}
+	rec(0, steps)
+}
@@ -0,0 +1,411 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ----------------- Item-based collaborative filtering -----------------
+
+// ItemKNN: filtrado colaborativo item-based.
+// Fit construye el índice item -> (user->rating) una vez; Recommend y Predict lo reutilizan.
+type ItemKNN struct {
+	Metric     SimMetric
+	NeighborK  int        // vecinos por candidato (0 -> todos los items que el user calificó)
+	MinSupport int        // usuarios en común mínimos para que dos items sean vecinos (0 = sin mínimo)
+	Shrinkage  float64    // sim * n/(n+Shrinkage), n = usuarios en común (0 = sin shrinkage)
+	Workers    int        // goroutines para puntuar candidatos (<= 1 -> secuencial)
+	Decay      *TimeDecay // peso temporal de los ratings (nil -> todos pesan igual)
+
+	st *knnState
+}
+
+// NewItemKNN crea un modelo item-based sin entrenar
+func NewItemKNN(metric SimMetric, neighborK int) *ItemKNN {
+	return &ItemKNN{Metric: metric, NeighborK: neighborK}
+}
+
+func (m *ItemKNN) Name() string {
+	return fmt.Sprintf("item-knn(%s,k=%d%s%s)", m.Metric, m.NeighborK, m.simConfig().suffix(), decaySuffix(m.Decay))
+}
+
+func (m *ItemKNN) simConfig() simConfig {
+	return simConfig{metric: m.Metric, minSupport: m.MinSupport, shrinkage: m.Shrinkage}
+}
+
+func (m *ItemKNN) Fit(ds *Dataset) error {
+	m.st = newKNNState(ds, m.Decay)
+	return nil
+}
+
+func (m *ItemKNN) Predict(user, item int) (float64, bool) {
+	if m.st == nil {
+		return 0, false
+	}
+	userRatings, ok := m.st.ds.UserRatings[user]
+	if !ok {
+		return m.st.globalMean, false
+	}
+	score, ok := scoreItem(m.st, userRatings, m.st.itemWeights(user), item, m.simConfig(), m.NeighborK)
+	if !ok {
+		return m.st.baseline(user), false
+	}
+	return score, true
+}
+
+func (m *ItemKNN) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.st == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	userRatings, ok := opts.ratingsFor(m.st.ds, user)
+	if !ok {
+		return nil, nil
+	}
+	// un perfil ad-hoc no tiene timestamps: todos sus ratings pesan 1
+	var weights func(item int) float64
+	if opts.Profile == nil {
+		weights = m.st.itemWeights(user)
+	}
+
+	// candidatos = todos los items excepto los ya vistos por user (y los filtrados)
+	candidates := m.st.candidates(user, userRatings, opts)
+
+	sc := m.simConfig()
+	recs, err := scoreCandidates(ctx, candidates, opts.TopK, m.Workers, func(itemV int) (float64, bool) {
+		return scoreItem(m.st, userRatings, weights, itemV, sc, m.NeighborK)
+	})
+	if opts.Explain {
+		// sólo se explica el top-K final, no cada candidato puntuado
+		m.explainItems(recs, userRatings, weights, opts.ExplainTop)
+	}
+	return recs, err
+}
+
+// scoreBatch: cuántos candidatos toma un worker de la cola compartida por vez.
+// Lotes chicos reparten bien la carga cuando unos items son mucho más caros que otros.
+const scoreBatch = 32
+
+// scoreCandidates: puntúa todos los candidatos con `workers` goroutines que toman
+// lotes de una cola compartida (un contador atómico) y mantienen cada una su
+// propio top-K; al final se mezclan los heaps. Los candidatos que score no puede
+// puntuar (ok=false) se descartan. Con workers <= 1 corre en la goroutine actual.
+// El resultado es idéntico para cualquier número de workers.
+// Si ctx se cancela, devuelve el mejor top-K de lo puntuado junto con ctx.Err().
+func scoreCandidates(ctx context.Context, candidates []int, topK, workers int, score func(item int) (float64, bool)) ([]ItemScore, error) {
+	var next atomic.Int64
+
+	work := func() *topKHeap {
+		top := newTopKHeap(topK)
+		for ctx.Err() == nil {
+			start := int(next.Add(scoreBatch)) - scoreBatch
+			if start >= len(candidates) {
+				break
+			}
+			end := min(start+scoreBatch, len(candidates))
+			for _, itemV := range candidates[start:end] {
+				if sc, ok := score(itemV); ok {
+					top.offer(ItemScore{MovieID: itemV, Score: sc})
+				}
+			}
+		}
+		return top
+	}
+
+	if workers <= 1 {
+		return work().sorted(), ctx.Err()
+	}
+
+	// buffer = workers: cada goroutine puede enviar su parcial y terminar aunque
+	// nadie lo lea, así no quedan goroutines colgadas al cancelar
+	out := make(chan *topKHeap, workers)
+	for w := 0; w < workers; w++ {
+		go func() { out <- work() }()
+	}
+
+	// merge de los top-K parciales
+	top := newTopKHeap(topK)
+	for i := 0; i < workers; i++ {
+		top.merge(<-out)
+	}
+
+	return top.sorted(), ctx.Err()
+}
+
+// itemNeighbors: los neighborK items calificados por el user más similares a itemV
+func itemNeighbors(st *knnState, userRatings map[int]float64, itemV int, sc simConfig, neighborK int) []neighbor {
+	// calcular similitudes entre itemV y los items que user calificó
+	simScores := make(map[int]float64, len(userRatings))
+	vecB := st.itemVecs[itemV]
+	for itemU := range userRatings {
+		if itemU == itemV {
+			continue
+		}
+		vecA := st.itemVecs[itemU]
+		simScores[itemU] = sc.sim(vecA, vecB)
+	}
+
+	// escoger top neighborK si se solicitó
+	if neighborK > 0 {
+		return topNneighborsFromScores(simScores, neighborK)
+	}
+	neighbors := make([]neighbor, 0, len(simScores))
+	for id, sc := range simScores {
+		neighbors = append(neighbors, neighbor{id: id, score: sc})
+	}
+	// orden fijo para que la suma (float) no dependa del orden del map
+	sortNeighbors(neighbors)
+	return neighbors
+}
+
+// scoreItem: weighted average de los ratings del user sobre los neighborK items
+// más similares a itemV. weights (nil -> 1) es el peso temporal de cada rating del
+// user. ok=false si ningún vecino aporta peso.
+func scoreItem(st *knnState, userRatings map[int]float64, weights func(item int) float64, itemV int, sc simConfig, neighborK int) (float64, bool) {
+	num := 0.0
+	den := 0.0
+	for _, nb := range itemNeighbors(st, userRatings, itemV, sc, neighborK) {
+		r := userRatings[nb.id] // rating del user sobre itemU
+		w := nb.score
+		if weights != nil {
+			w *= weights(nb.id)
+		}
+		num += w * r
+		den += abs(w)
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// explainItems: adjunta a cada recomendación item-based los items calificados que más aportaron
+func (m *ItemKNN) explainItems(recs []ItemScore, userRatings map[int]float64, weights func(item int) float64, top int) {
+	for i := range recs {
+		neighbors := itemNeighbors(m.st, userRatings, recs[i].MovieID, m.simConfig(), m.NeighborK)
+		contribs := make([]Contribution, 0, len(neighbors))
+		for _, nb := range neighbors {
+			c := Contribution{ID: nb.id, Similarity: nb.score, Rating: userRatings[nb.id]}
+			if weights != nil {
+				if c.Decay = weights(nb.id); c.Decay == 0 {
+					continue // fuera de la ventana: no aportó
+				}
+			}
+			contribs = append(contribs, c)
+		}
+		recs[i].Why = newExplanation(ExplainItems, m.Metric, m.NeighborK, contribs, top)
+	}
+}
+
+// ----------------- User-based collaborative filtering -----------------
+
+// Valores por defecto de UserKNN
+const (
+	DefaultMinSimilarity = 0.05 // vecinos por debajo no aportan (ruido)
+	DefaultMinNeighbors  = 2    // vecinos que calificaron el item para puntuarlo
+)
+
+// UserKNN: filtrado colaborativo user-based con predicción de Resnick:
+//
+//	pred(u,i) = media(u) + sum_v sim(u,v)*(r(v,i) - media(v)) / sum_v |sim(u,v)|
+//
+// así un vecino que califica todo alto no infla las predicciones.
+// Los vecinos de cada usuario se calculan la primera vez que se piden y quedan
+// cacheados hasta el próximo Fit (cambiar parámetros requiere volver a llamar a Fit).
+type UserKNN struct {
+	Metric        SimMetric
+	NeighborK     int        // cuántos vecinos usuarios considerar
+	MinSimilarity float64    // similitud mínima para ser vecino
+	MinNeighbors  int        // mínimo de vecinos que calificaron el item para puntuarlo
+	MinSupport    int        // items en común mínimos para que dos usuarios sean vecinos (0 = sin mínimo)
+	Shrinkage     float64    // sim * n/(n+Shrinkage), n = items en común (0 = sin shrinkage)
+	Decay         *TimeDecay // peso temporal de los ratings (nil -> todos pesan igual)
+
+	st        *knnState
+	userVecs  map[int]sparseVec
+	neighbors sync.Map // user -> []neighbor
+}
+
+// NewUserKNN crea un modelo user-based sin entrenar con umbrales por defecto
+func NewUserKNN(metric SimMetric, neighborK int) *UserKNN {
+	return &UserKNN{
+		Metric:        metric,
+		NeighborK:     neighborK,
+		MinSimilarity: DefaultMinSimilarity,
+		MinNeighbors:  DefaultMinNeighbors,
+	}
+}
+
+func (m *UserKNN) Name() string {
+	return fmt.Sprintf("user-knn(%s,k=%d%s%s)", m.Metric, m.NeighborK, m.simConfig().suffix(), decaySuffix(m.Decay))
+}
+
+func (m *UserKNN) simConfig() simConfig {
+	return simConfig{metric: m.Metric, minSupport: m.MinSupport, shrinkage: m.Shrinkage}
+}
+
+func (m *UserKNN) Fit(ds *Dataset) error {
+	m.st = newKNNState(ds, m.Decay)
+	m.userVecs = make(map[int]sparseVec, len(ds.UserRatings))
+	for u, items := range ds.UserRatings {
+		m.userVecs[u] = toSparseWeighted(items, m.st.itemWeights(u))
+	}
+	m.neighbors.Clear()
+	return nil
+}
+
+// userNeighbors: top neighborK usuarios más similares a user (cacheado salvo
+// cache=false, que se usa para perfiles ad-hoc).
+// Si ctx se cancela a mitad de camino devuelve ctx.Err() y no cachea nada.
+func (m *UserKNN) userNeighbors(ctx context.Context, user int, targetRatings map[int]float64, cache bool) ([]neighbor, error) {
+	if cached, ok := m.neighbors.Load(user); ok && cache {
+		return cached.([]neighbor), nil
+	}
+	target, ok := m.userVecs[user]
+	if !cache || !ok {
+		// perfil ad-hoc: sin timestamps, sin pesos
+		target = toSparse(targetRatings)
+	}
+	sc := m.simConfig()
+	// construir similitudes entre user y todos los otros users
+	userSims := make(map[int]float64)
+	n := 0
+	for other, vec := range m.userVecs {
+		if other == user {
+			continue
+		}
+		// revisar cancelación cada 256 usuarios, no en cada similitud
+		if n++; n%256 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		sim := sc.sim(target, vec)
+		if sim < m.MinSimilarity || sim == 0 {
+			continue
+		}
+		userSims[other] = sim
+	}
+	neighbors := topNneighborsFromScores(userSims, m.NeighborK)
+	if cache {
+		m.neighbors.Store(user, neighbors)
+	}
+	return neighbors, nil
+}
+
+// predictFromNeighbors: Resnick sobre los vecinos que calificaron item, partiendo
+// de la media del usuario objetivo; con Decay cada vecino pesa sim*decay de su rating.
+// ok=false si lo calificaron menos de MinNeighbors vecinos.
+func (m *UserKNN) predictFromNeighbors(targetMean float64, neighbors []neighbor, item int) (float64, bool) {
+	num := 0.0
+	den := 0.0
+	raters := 0
+	for _, nb := range neighbors {
+		if r, ok := m.st.ds.UserRatings[nb.id][item]; ok {
+			w := nb.score * m.st.weight(nb.id, item)
+			if w == 0 {
+				continue
+			}
+			num += w * (r - m.st.userMeans[nb.id])
+			den += abs(w)
+			raters++
+		}
+	}
+	if den == 0 || raters < max(m.MinNeighbors, 1) {
+		return 0, false
+	}
+	return clamp01(targetMean + num/den), true
+}
+
+func (m *UserKNN) Predict(user, item int) (float64, bool) {
+	if m.st == nil {
+		return 0, false
+	}
+	targetRatings, ok := m.st.ds.UserRatings[user]
+	if !ok {
+		return m.st.globalMean, false
+	}
+	neighbors, _ := m.userNeighbors(context.Background(), user, targetRatings, true)
+	score, ok := m.predictFromNeighbors(m.st.baseline(user), neighbors, item)
+	if !ok {
+		return m.st.baseline(user), false
+	}
+	return score, true
+}
+
+func (m *UserKNN) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.st == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	targetRatings, ok := opts.ratingsFor(m.st.ds, user)
+	if !ok {
+		return nil, nil
+	}
+	adhoc := opts.Profile != nil
+	targetMean := m.st.baseline(user)
+	if adhoc {
+		targetMean = meanOf(targetRatings)
+	}
+
+	// seleccionar vecinos top neighborK
+	neighbors, err := m.userNeighbors(ctx, user, targetRatings, !adhoc)
+	if err != nil {
+		return nil, err
+	}
+	if len(neighbors) == 0 {
+		return nil, nil
+	}
+
+	// candidatos = items que los vecinos han calificado pero el target no
+	candidates := make(map[int]struct{})
+	for _, nb := range neighbors {
+		for it := range m.st.ds.UserRatings[nb.id] {
+			if _, done := candidates[it]; !done && opts.isCandidate(user, it, targetRatings) {
+				candidates[it] = struct{}{}
+			}
+		}
+	}
+
+	// para cada candidato, predicción de Resnick; sin suficientes vecinos no se puntúa
+	scores := make(map[int]float64, len(candidates))
+	for it := range candidates {
+		if err := ctx.Err(); err != nil {
+			return topKFromMap(scores, opts.TopK), err
+		}
+		if score, ok := m.predictFromNeighbors(targetMean, neighbors, it); ok {
+			scores[it] = score
+		}
+	}
+
+	recs := topKFromMap(scores, opts.TopK)
+	if opts.Explain {
+		m.explainUsers(recs, neighbors, opts.ExplainTop)
+	}
+	return recs, nil
+}
+
+// explainUsers: adjunta a cada recomendación user-based los vecinos que calificaron el item
+func (m *UserKNN) explainUsers(recs []ItemScore, neighbors []neighbor, top int) {
+	for i := range recs {
+		contribs := make([]Contribution, 0, len(neighbors))
+		for _, nb := range neighbors {
+			if r, ok := m.st.ds.UserRatings[nb.id][recs[i].MovieID]; ok {
+				c := Contribution{ID: nb.id, Similarity: nb.score, Rating: r}
+				if m.st.decay != nil {
+					if c.Decay = m.st.weight(nb.id, recs[i].MovieID); c.Decay == 0 {
+						continue
+					}
+				}
+				contribs = append(contribs, c)
+			}
+		}
+		recs[i].Why = newExplanation(ExplainUsers, m.Metric, m.NeighborK, contribs, top)
+	}
+}
@@ -0,0 +1,97 @@
+package ml
+
+import (
+	"math"
+)
+
+// ----------------- Cold start: onboarding de usuarios nuevos -----------------
+
+// ratingBins: histograma de ratings en pasos de media estrella (0.5..5)
+const ratingBins = 10
+
+// SeedMovies elige n películas para preguntarle a un usuario nuevo. Cada
+// película vale log(#ratings) * entropía de sus ratings: conocidas (es probable
+// que el usuario la haya visto) y polarizantes (su respuesta dice algo). Se eligen
+// de a una penalizando los géneros ya cubiertos para que la muestra sea diversa.
+// cat puede ser nil (sin diversidad por género).
+func SeedMovies(ds *Dataset, cat *Catalog, n int) []int {
+	hist := make(map[int]*[ratingBins]int)
+	for _, items := range ds.UserRatings {
+		for it, r := range items {
+			h, ok := hist[it]
+			if !ok {
+				h = new([ratingBins]int)
+				hist[it] = h
+			}
+			bin := int(math.Round(r*ratingBins)) - 1
+			h[min(max(bin, 0), ratingBins-1)]++
+		}
+	}
+
+	base := make(map[int]float64, len(hist))
+	for it, h := range hist {
+		total := 0
+		for _, c := range h {
+			total += c
+		}
+		entropy := 0.0
+		for _, c := range h {
+			if c > 0 {
+				p := float64(c) / float64(total)
+				entropy -= p * math.Log2(p)
+			}
+		}
+		base[it] = math.Log(float64(total)) * entropy
+	}
+
+	covered := make(map[string]int)
+	seeds := make([]int, 0, n)
+	for len(seeds) < n && len(base) > 0 {
+		best, bestVal := 0, math.Inf(-1)
+		for it, v := range base {
+			val := v * genreNovelty(cat, it, covered)
+			if val > bestVal || (val == bestVal && it < best) {
+				best, bestVal = it, val
+			}
+		}
+		seeds = append(seeds, best)
+		delete(base, best)
+		if cat != nil {
+			for _, g := range cat.Genres(best) {
+				covered[g]++
+			}
+		}
+	}
+	return seeds
+}
+
+// genreNovelty: 1 si ninguno de los géneros de la película está cubierto,
+// baja a medida que sus géneros se repiten en la muestra
+func genreNovelty(cat *Catalog, item int, covered map[string]int) float64 {
+	if cat == nil {
+		return 1
+	}
+	genres := cat.Genres(item)
+	if len(genres) == 0 {
+		return 1
+	}
+	rep := 0.0
+	for _, g := range genres {
+		rep += float64(covered[g])
+	}
+	return 1 / (1 + rep/float64(len(genres)))
+}
+
+// OnboardingProfile convierte las respuestas rápidas del usuario (estrellas
+// 0.5..5; 0 = "no la vi") en un perfil ad-hoc para RecommendOptions.Profile.
+// El perfil no se agrega al Dataset.
+func OnboardingProfile(answers map[int]float64) map[int]float64 {
+	profile := make(map[int]float64, len(answers))
+	for it, stars := range answers {
+		if stars <= 0 {
+			continue
+		}
+		profile[it] = NormalizeRating(min(stars, 5))
+	}
+	return profile
+}
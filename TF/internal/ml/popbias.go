@@ -0,0 +1,249 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ----------------- Sesgo de popularidad -----------------
+
+// Tier: franja de popularidad de una película
+type Tier int
+
+const (
+	HeadTier Tier = iota // las más populares
+	MidTier
+	TailTier // nicho (long tail)
+)
+
+func (t Tier) String() string {
+	switch t {
+	case HeadTier:
+		return "head"
+	case MidTier:
+		return "mid"
+	default:
+		return "tail"
+	}
+}
+
+// Tiers: las tres franjas, en orden
+var Tiers = []Tier{HeadTier, MidTier, TailTier}
+
+// Cortes por defecto de NewPopularityTiers
+const (
+	DefaultHeadShare = 0.2
+	DefaultTailShare = 0.2
+)
+
+// PopularityTiers clasifica las películas por cantidad de ratings: head son las
+// más populares que juntas suman HeadShare de los ratings, tail las menos
+// populares que suman TailShare, y mid el resto. Las películas sin ratings son tail.
+type PopularityTiers struct {
+	HeadShare, TailShare float64
+	Counts               map[int]int // item -> ratings
+	tier                 map[int]Tier
+}
+
+// NewPopularityTiers calcula las franjas sobre ds (shares en 0 -> 0.2)
+func NewPopularityTiers(ds *Dataset, headShare, tailShare float64) *PopularityTiers {
+	if headShare <= 0 {
+		headShare = DefaultHeadShare
+	}
+	if tailShare <= 0 {
+		tailShare = DefaultTailShare
+	}
+	counts := make(map[int]int)
+	total := 0
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			counts[it]++
+			total++
+		}
+	}
+	items := make([]int, 0, len(counts))
+	for it := range counts {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if counts[items[i]] != counts[items[j]] {
+			return counts[items[i]] > counts[items[j]]
+		}
+		return items[i] < items[j]
+	})
+
+	pt := &PopularityTiers{HeadShare: headShare, TailShare: tailShare, Counts: counts, tier: make(map[int]Tier, len(items))}
+	acc := 0
+	for _, it := range items {
+		share := float64(acc) / float64(total) // ratings acumulados antes de este item
+		switch {
+		case share < headShare:
+			pt.tier[it] = HeadTier
+		case share < 1-tailShare:
+			pt.tier[it] = MidTier
+		default:
+			pt.tier[it] = TailTier
+		}
+		acc += counts[it]
+	}
+	return pt
+}
+
+// Of devuelve la franja de una película
+func (pt *PopularityTiers) Of(item int) Tier {
+	if t, ok := pt.tier[item]; ok {
+		return t
+	}
+	return TailTier
+}
+
+// Size: cuántas películas (con ratings) hay en la franja
+func (pt *PopularityTiers) Size(t Tier) int {
+	n := 0
+	for _, x := range pt.tier {
+		if x == t {
+			n++
+		}
+	}
+	return n
+}
+
+// ----------------- re-rankers de des-sesgo -----------------
+
+// XQuAD: re-ranker de Abdollahpouri et al. (2019) sobre dos categorías, head y
+// long tail (mid+tail). En cada paso elige el candidato que maximiza
+//
+//	(1-Lambda)*relevancia + Lambda*sum_c P(c|u) * [i ∈ c] * prod_{j elegido} (1 - [j ∈ c])
+//
+// con P(c|u) la proporción de la historia del usuario en cada categoría: una vez
+// que la lista cubre una categoría, sólo la relevancia suma. Necesita Fit (lo
+// llama Reranked.Fit).
+type XQuAD struct {
+	Lambda    float64
+	HeadShare float64 // ver PopularityTiers (0 -> 0.2)
+
+	ds    *Dataset
+	tiers *PopularityTiers
+}
+
+// NewXQuAD crea el re-ranker con el peso de la cobertura de la long tail
+func NewXQuAD(lambda float64) *XQuAD {
+	return &XQuAD{Lambda: lambda}
+}
+
+func (r *XQuAD) Name() string {
+	return fmt.Sprintf("xquad(λ=%.2f)", r.Lambda)
+}
+
+func (r *XQuAD) Fit(ds *Dataset) error {
+	r.ds = ds
+	r.tiers = NewPopularityTiers(ds, r.HeadShare, 0)
+	return nil
+}
+
+func (r *XQuAD) isHead(item int) bool {
+	return r.tiers.Of(item) == HeadTier
+}
+
+func (r *XQuAD) Rerank(user int, items []ItemScore, k int) []ItemScore {
+	if r.tiers == nil {
+		return truncate(items, k)
+	}
+	k = min(k, len(items))
+
+	// P(head|u), P(tail|u) según lo que el usuario calificó (0.5/0.5 sin historia)
+	pHead := 0.5
+	if hist := r.ds.UserRatings[user]; len(hist) > 0 {
+		n := 0
+		for it := range hist {
+			if r.isHead(it) {
+				n++
+			}
+		}
+		pHead = float64(n) / float64(len(hist))
+	}
+
+	rel := normalizeScores(items)
+	used := make([]bool, len(items))
+	out := make([]ItemScore, 0, k)
+	headCovered, tailCovered := false, false
+	for len(out) < k {
+		best, bestVal := -1, math.Inf(-1)
+		for i, it := range items {
+			if used[i] {
+				continue
+			}
+			div := 0.0
+			if r.isHead(it.MovieID) && !headCovered {
+				div = pHead
+			} else if !r.isHead(it.MovieID) && !tailCovered {
+				div = 1 - pHead
+			}
+			if val := (1-r.Lambda)*rel[it.MovieID] + r.Lambda*div; val > bestVal {
+				best, bestVal = i, val
+			}
+		}
+		used[best] = true
+		out = append(out, items[best])
+		if r.isHead(items[best].MovieID) {
+			headCovered = true
+		} else {
+			tailCovered = true
+		}
+	}
+	return out
+}
+
+// InversePropensity: reordena por score / propensión^Beta, con el score
+// normalizado a [0,1] (min-max por lista, así un score negativo no premia a los
+// populares) y la propensión de exposición estimada como (ratings del item + 1) /
+// (ratings del más popular + 1).
+// Beta=0 deja el orden original; valores mayores favorecen cada vez más el nicho.
+// Necesita Fit (lo llama Reranked.Fit).
+type InversePropensity struct {
+	Beta float64
+
+	counts map[int]int
+	maxC   int
+}
+
+// NewInversePropensity crea el re-ranker con el exponente indicado
+func NewInversePropensity(beta float64) *InversePropensity {
+	return &InversePropensity{Beta: beta}
+}
+
+func (r *InversePropensity) Name() string {
+	return fmt.Sprintf("ipw(β=%.2f)", r.Beta)
+}
+
+func (r *InversePropensity) Fit(ds *Dataset) error {
+	r.counts = make(map[int]int)
+	r.maxC = 0
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			r.counts[it]++
+			r.maxC = max(r.maxC, r.counts[it])
+		}
+	}
+	return nil
+}
+
+func (r *InversePropensity) Rerank(user int, items []ItemScore, k int) []ItemScore {
+	if r.counts == nil {
+		return truncate(items, k)
+	}
+	rel := normalizeScores(items)
+	adjusted := make(map[int]float64, len(items))
+	byID := make(map[int]ItemScore, len(items))
+	for _, it := range items {
+		prop := float64(r.counts[it.MovieID]+1) / float64(r.maxC+1)
+		adjusted[it.MovieID] = rel[it.MovieID] / math.Pow(prop, r.Beta)
+		byID[it.MovieID] = it
+	}
+	out := topKFromMap(adjusted, k)
+	for i := range out {
+		out[i] = byID[out[i].MovieID] // score original, orden nuevo
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package ml
+
+import (
+	"context"
+)
+
+// ----------------- Popularidad -----------------
+
+// popularityDamping: ratings "virtuales" con la media global que se suman a cada
+// película al estimar su rating, para que 1 voto de 5 estrellas no gane
+const popularityDamping = 5
+
+// Popularity: baseline no personalizado. Recommend devuelve las películas más
+// calificadas que el usuario no vio; Predict la media (amortiguada) de la película.
+type Popularity struct {
+	ds         *Dataset
+	counts     map[int]int
+	sums       map[int]float64
+	globalMean float64
+	ranked     []ItemScore // todas las películas, de más a menos popular
+}
+
+// NewPopularity crea un recomendador por popularidad sin entrenar
+func NewPopularity() *Popularity {
+	return &Popularity{}
+}
+
+func (m *Popularity) Name() string {
+	return "popularity"
+}
+
+func (m *Popularity) Fit(ds *Dataset) error {
+	counts := make(map[int]int)
+	sums := make(map[int]float64)
+	total := 0.0
+	for _, items := range ds.UserRatings {
+		for it, r := range items {
+			counts[it]++
+			sums[it] += r
+			total += r
+		}
+	}
+	scores := make(map[int]float64, len(counts))
+	n := 0
+	for it, c := range counts {
+		scores[it] = float64(c)
+		n += c
+	}
+
+	m.ds = ds
+	m.counts = counts
+	m.sums = sums
+	m.globalMean = 0
+	if n > 0 {
+		m.globalMean = total / float64(n)
+	}
+	m.ranked = topKFromMap(scores, len(scores))
+	return nil
+}
+
+// Count: cuántos usuarios calificaron la película
+func (m *Popularity) Count(item int) int {
+	return m.counts[item]
+}
+
+func (m *Popularity) Predict(user, item int) (float64, bool) {
+	if m.ds == nil {
+		return 0, false
+	}
+	c, ok := m.counts[item]
+	if !ok {
+		return m.globalMean, false
+	}
+	return (m.sums[item] + popularityDamping*m.globalMean) / float64(c+popularityDamping), true
+}
+
+func (m *Popularity) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	seen, _ := opts.ratingsFor(m.ds, user)
+	out := make([]ItemScore, 0, opts.TopK)
+	for _, it := range m.ranked {
+		if len(out) >= opts.TopK {
+			break
+		}
+		if !opts.isCandidate(user, it.MovieID, seen) {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out, nil
+}
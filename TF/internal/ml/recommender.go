@@ -2,6 +2,8 @@ package ml
 
 import (
 	"container/heap"
+	"context"
+	"errors"
 	"sort"
 )
 
@@ -14,10 +16,80 @@ const (
 	JaccardSim
 )
 
+// String devuelve el nombre corto de la métrica
+func (m SimMetric) String() string {
+	switch m {
+	case CosineSim:
+		return "cosine"
+	case PearsonSim:
+		return "pearson"
+	case JaccardSim:
+		return "jaccard"
+	default:
+		return "unknown"
+	}
+}
+
 // ItemScore guarda predicción/score para un item
 type ItemScore struct {
-	MovieID int
-	Score   float64
+	MovieID int          `json:"movie_id"`
+	Score   float64      `json:"score"`
+	Why     *Explanation `json:"why,omitempty"` // sólo con RecommendOptions.Explain
+}
+
+// ----------------- interfaz común -----------------
+
+// ErrNotFitted se devuelve cuando se usa un modelo antes de llamar a Fit
+var ErrNotFitted = errors.New("ml: modelo sin entrenar (llamar a Fit primero)")
+
+// RecommendOptions: parámetros de una llamada a Recommend
+type RecommendOptions struct {
+	TopK int // cuántas recomendaciones devolver
+
+	// Explain adjunta a cada resultado los vecinos que más aportaron (ItemScore.Why);
+	// ExplainTop limita cuántos (0 -> 5). Los modelos sin vecinos lo ignoran.
+	Explain    bool
+	ExplainTop int
+
+	// Filter restringe los candidatos antes de puntuarlos (nil = sin filtro)
+	Filter Filter
+
+	// Profile: ratings ad-hoc (normalizados 0..1) que reemplazan a los del
+	// dataset para esta llamada, p.ej. las respuestas del onboarding de un
+	// usuario nuevo. El usuario no necesita existir en el Dataset.
+	Profile map[int]float64
+}
+
+// ratingsFor: ratings con los que se recomienda a user: el perfil ad-hoc si lo
+// hay, si no los del dataset. ok=false si no hay ninguno.
+func (o RecommendOptions) ratingsFor(ds *Dataset, user int) (map[int]float64, bool) {
+	if o.Profile != nil {
+		return o.Profile, len(o.Profile) > 0
+	}
+	r, ok := ds.UserRatings[user]
+	return r, ok
+}
+
+// isCandidate: item no visto por el usuario y aceptado por el filtro
+func (o RecommendOptions) isCandidate(user, item int, seen map[int]float64) bool {
+	if _, ok := seen[item]; ok {
+		return false
+	}
+	return o.Filter == nil || o.Filter.Allow(user, item)
+}
+
+// Recommender: interfaz común a todos los algoritmos.
+// Fit entrena (o re-entrena) el estado del modelo sobre el dataset; Predict y
+// Recommend reutilizan ese estado entre llamadas y son seguros para uso concurrente.
+type Recommender interface {
+	Name() string
+	Fit(ds *Dataset) error
+	// Predict devuelve el rating estimado (escala normalizada 0..1) y si el modelo
+	// pudo realmente predecirlo; si no, el valor es un baseline (media del usuario)
+	Predict(user, item int) (float64, bool)
+	// Recommend respeta ctx: al cancelarse devuelve lo mejor encontrado hasta
+	// entonces (puede ser nil) junto con ctx.Err(), sin dejar goroutines vivas
+	Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error)
 }
 
 // ----------------- helpers -----------------
@@ -36,18 +108,13 @@ func BuildItemIndex(ds *Dataset) map[int]map[int]float64 {
 	return itemIndex
 }
 
-// simBetween: dispatch a la función correspondiente
-func simBetween(a, b map[int]float64, metric SimMetric) float64 {
-	switch metric {
-	case CosineSim:
-		return Cosine(a, b)
-	case PearsonSim:
-		return Pearson(a, b)
-	case JaccardSim:
-		return Jaccard(a, b)
-	default:
-		return Cosine(a, b)
+// betterScore: orden total de resultados (score desc, MovieID asc en empates),
+// así secuencial y paralelo devuelven exactamente la misma lista
+func betterScore(a, b ItemScore) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
 	}
+	return a.MovieID < b.MovieID
 }
 
 // topKFromMap: ordena y devuelve top K ItemScore
@@ -56,13 +123,43 @@ func topKFromMap(scores map[int]float64, k int) []ItemScore {
 	for m, sc := range scores {
 		top = append(top, ItemScore{MovieID: m, Score: sc})
 	}
-	sort.Slice(top, func(i, j int) bool { return top[i].Score > top[j].Score })
+	sort.Slice(top, func(i, j int) bool { return betterScore(top[i], top[j]) })
 	if len(top) > k {
 		return top[:k]
 	}
 	return top
 }
 
+// normalizeScores: min-max de los scores de una lista a [0,1] (todos iguales -> 1),
+// para poder combinar modelos con escalas distintas
+func normalizeScores(items []ItemScore) map[int]float64 {
+	out := make(map[int]float64, len(items))
+	if len(items) == 0 {
+		return out
+	}
+	lo, hi := items[0].Score, items[0].Score
+	for _, it := range items {
+		lo = min(lo, it.Score)
+		hi = max(hi, it.Score)
+	}
+	for _, it := range items {
+		if hi == lo {
+			out[it.MovieID] = 1
+		} else {
+			out[it.MovieID] = (it.Score - lo) / (hi - lo)
+		}
+	}
+	return out
+}
+
+// truncate: primeros k elementos de una lista ya ordenada
+func truncate(items []ItemScore, k int) []ItemScore {
+	if len(items) > k {
+		return items[:k]
+	}
+	return items
+}
+
 // ----------------- KNN utilities -----------------
 
 // simple min-heap for top-N neighbors
@@ -72,8 +169,14 @@ type neighbor struct {
 }
 type minHeap []neighbor
 
-func (h minHeap) Len() int            { return len(h) }
-func (h minHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+// Less: el "peor" vecino queda arriba; en empate de score pierde el id mayor
+func (h minHeap) Len() int { return len(h) }
+func (h minHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	return h[i].id > h[j].id
+}
 func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
 func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(neighbor)) }
 func (h *minHeap) Pop() interface{} {
@@ -90,7 +193,7 @@ func topNneighborsFromScores(scores map[int]float64, n int) []neighbor {
 	for id, sc := range scores {
 		if h.Len() < n {
 			heap.Push(h, neighbor{id: id, score: sc})
-		} else if sc > (*h)[0].score {
+		} else if top := (*h)[0]; sc > top.score || (sc == top.score && id < top.id) {
 			heap.Pop(h)
 			heap.Push(h, neighbor{id: id, score: sc})
 		}
@@ -102,219 +205,209 @@ func topNneighborsFromScores(scores map[int]float64, n int) []neighbor {
 	return res
 }
 
-// ----------------- Item-based collaborative filtering -----------------
+// sortNeighbors: mismo orden que topNneighborsFromScores (score desc, id asc)
+func sortNeighbors(nbs []neighbor) {
+	sort.Slice(nbs, func(i, j int) bool {
+		if nbs[i].score != nbs[j].score {
+			return nbs[i].score > nbs[j].score
+		}
+		return nbs[i].id < nbs[j].id
+	})
+}
 
-// RecommendItemBased:
-// - ds: dataset ya cargado
-// - user: userId objetivo
-// - topK: cuántas recomendaciones devolver
-// - metric: similitud a usar
-// - neighborK: cuántos vecinos por candidato considerar (si 0 -> usar todos los items que user calificó)
-func RecommendItemBased(ds *Dataset, user int, topK int, metric SimMetric, neighborK int) []ItemScore {
-	userRatings, ok := ds.UserRatings[user]
-	if !ok {
-		return nil
-	}
-	itemIndex := BuildItemIndex(ds)
+// topKHeap: top-K acotado de ItemScore (min-heap con el peor resultado arriba)
+type topKHeap struct {
+	k     int
+	items []ItemScore
+}
 
-	// candidatos = todos los items excepto los ya vistos por user
-	candidates := make([]int, 0)
-	for it := range itemIndex {
-		if _, seen := userRatings[it]; !seen {
-			candidates = append(candidates, it)
-		}
+func newTopKHeap(k int) *topKHeap {
+	if k < 0 {
+		k = 0
 	}
+	return &topKHeap{k: k, items: make([]ItemScore, 0, k)}
+}
 
-	scores := make(map[int]float64)
-
-	for _, itemV := range candidates {
-		// calcular similitudes entre itemV y los items que user calificó
-		simScores := make(map[int]float64, len(userRatings))
-		vecB := itemIndex[itemV]
-		for itemU := range userRatings {
-			vecA := itemIndex[itemU]
-			simScores[itemU] = simBetween(vecA, vecB, metric)
-		}
+func (h *topKHeap) Len() int           { return len(h.items) }
+func (h *topKHeap) Less(i, j int) bool { return betterScore(h.items[j], h.items[i]) }
+func (h *topKHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *topKHeap) Push(x interface{}) { h.items = append(h.items, x.(ItemScore)) }
+func (h *topKHeap) Pop() interface{} {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[:n-1]
+	return x
+}
 
-		// escoger top neighborK si se solicitó
-		var neighbors []neighbor
-		if neighborK > 0 {
-			neighbors = topNneighborsFromScores(simScores, neighborK)
-		} else {
-			// convertir todos
-			neighbors = make([]neighbor, 0, len(simScores))
-			for id, sc := range simScores {
-				neighbors = append(neighbors, neighbor{id: id, score: sc})
-			}
-		}
+// offer: inserta it si entra en el top-K
+func (h *topKHeap) offer(it ItemScore) {
+	if h.k == 0 {
+		return
+	}
+	if len(h.items) < h.k {
+		heap.Push(h, it)
+		return
+	}
+	if betterScore(it, h.items[0]) {
+		h.items[0] = it
+		heap.Fix(h, 0)
+	}
+}
 
-		num := 0.0
-		den := 0.0
-		for _, nb := range neighbors {
-			r := userRatings[nb.id] // rating del user sobre itemU
-			num += nb.score * r
-			den += abs(nb.score)
-		}
-		if den != 0 {
-			scores[itemV] = num / den
-		} else {
-			scores[itemV] = 0
-		}
+// merge: incorpora los resultados de otro heap (p.ej. el de otro worker)
+func (h *topKHeap) merge(o *topKHeap) {
+	for _, it := range o.items {
+		h.offer(it)
 	}
+}
 
-	return topKFromMap(scores, topK)
+// sorted: resultados de mejor a peor
+func (h *topKHeap) sorted() []ItemScore {
+	out := make([]ItemScore, len(h.items))
+	copy(out, h.items)
+	sort.Slice(out, func(i, j int) bool { return betterScore(out[i], out[j]) })
+	return out
 }
 
-// ----------------- User-based collaborative filtering -----------------
+// ----------------- estado entrenado compartido -----------------
+
+// knnState: índice item -> (user->rating), sus vectores ordenados y medias por
+// usuario, construidos una sola vez en Fit y compartidos por todas las llamadas.
+// Con decay != nil cada componente de los vectores lleva el peso temporal del rating.
+type knnState struct {
+	ds         *Dataset
+	decay      *TimeDecay
+	itemIndex  map[int]map[int]float64
+	itemVecs   map[int]sparseVec
+	userMeans  map[int]float64
+	globalMean float64
+}
 
-// RecommendUserBased:
-// - predice usando los K vecinos usuarios más similares
-// - neighborK = cuántos vecinos usuarios considerar
-func RecommendUserBased(ds *Dataset, user int, topK int, metric SimMetric, neighborK int) []ItemScore {
-	targetRatings, ok := ds.UserRatings[user]
-	if !ok {
-		return nil
+func newKNNState(ds *Dataset, decay *TimeDecay) *knnState {
+	st := &knnState{
+		ds:        ds,
+		decay:     decay.resolve(ds),
+		itemIndex: BuildItemIndex(ds),
+		userMeans: make(map[int]float64, len(ds.UserRatings)),
+	}
+	st.itemVecs = make(map[int]sparseVec, len(st.itemIndex))
+	for it, users := range st.itemIndex {
+		st.itemVecs[it] = toSparseWeighted(users, st.userWeights(it))
 	}
-	// construir similitudes entre user y todos los otros users
-	userSims := make(map[int]float64)
-	for other, ratings := range ds.UserRatings {
-		if other == user {
-			continue
+	total, n := 0.0, 0
+	for u, items := range ds.UserRatings {
+		sum := 0.0
+		for _, r := range items {
+			sum += r
 		}
-		userSims[other] = simBetween(targetRatings, ratings, metric)
-		if userSims[other] < 0.05 { // este threshold lo vas a tunear luego
-			continue
+		if len(items) > 0 {
+			st.userMeans[u] = sum / float64(len(items))
 		}
+		total += sum
+		n += len(items)
+	}
+	if n > 0 {
+		st.globalMean = total / float64(n)
 	}
+	return st
+}
+
+// weight: peso temporal del rating (user,item); 1 sin decay o sin timestamp
+func (st *knnState) weight(user, item int) float64 {
+	return st.decay.Weight(st.ds.Timestamp(user, item))
+}
 
-	// seleccionar vecinos top neighborK
-	neighbors := topNneighborsFromScores(userSims, neighborK)
-	if len(neighbors) == 0 {
+// userWeights: pesos por usuario de los ratings de item (nil sin decay)
+func (st *knnState) userWeights(item int) func(user int) float64 {
+	if st.decay == nil {
 		return nil
 	}
+	return func(user int) float64 { return st.weight(user, item) }
+}
 
-	// candidatos = items que los vecinos han calificado pero el target no
-	candidatesMap := make(map[int]struct{})
-	for _, nb := range neighbors {
-		other := nb.id
-		for it := range ds.UserRatings[other] {
-			if _, seen := targetRatings[it]; !seen {
-				candidatesMap[it] = struct{}{}
-			}
-		}
-	}
-	candidates := make([]int, 0, len(candidatesMap))
-	for it := range candidatesMap {
-		candidates = append(candidates, it)
-	}
-
-	// para cada candidato, agregar weighted avg de vecinos
-	scores := make(map[int]float64)
-	for _, it := range candidates {
-		num := 0.0
-		den := 0.0
-		for _, nb := range neighbors {
-			other := nb.id
-			sim := nb.score
-			if r, ok := ds.UserRatings[other][it]; ok {
-				num += sim * r
-				den += abs(sim)
-			}
-		}
-		if den != 0 {
-			scores[it] = num / den
-		} else {
-			scores[it] = 0
-		}
+// itemWeights: pesos por item de los ratings de user (nil sin decay)
+func (st *knnState) itemWeights(user int) func(item int) float64 {
+	if st.decay == nil {
+		return nil
 	}
-
-	return topKFromMap(scores, topK)
+	return func(item int) float64 { return st.weight(user, item) }
 }
 
-// ----------------- util -----------------
-func abs(a float64) float64 {
-	if a < 0 {
-		return -a
+// baseline: media del usuario, o media global si no se conoce
+func (st *knnState) baseline(user int) float64 {
+	if m, ok := st.userMeans[user]; ok {
+		return m
 	}
-	return a
+	return st.globalMean
 }
 
-func scoreItem(ds *Dataset, userRatings map[int]float64, itemIndex map[int]map[int]float64, itemV int, metric SimMetric, neighborK int) float64 {
-	simScores := make(map[int]float64, len(userRatings))
-	vecB := itemIndex[itemV]
-
-	for itemU := range userRatings {
-		vecA := itemIndex[itemU]
-		simScores[itemU] = simBetween(vecA, vecB, metric)
+// candidates: todos los items del índice excepto los ya vistos por el usuario
+// y los que rechaza opts.Filter
+func (st *knnState) candidates(user int, seen map[int]float64, opts RecommendOptions) []int {
+	out := make([]int, 0, len(st.itemIndex))
+	for it := range st.itemIndex {
+		if opts.isCandidate(user, it, seen) {
+			out = append(out, it)
+		}
 	}
+	return out
+}
 
-	neighbors := topNneighborsFromScores(simScores, neighborK)
+// ----------------- atajos sin estado -----------------
 
-	num := 0.0
-	den := 0.0
-	for _, nb := range neighbors {
-		r := userRatings[nb.id]
-		num += nb.score * r
-		if nb.score < 0 {
-			den -= nb.score
-		} else {
-			den += nb.score
-		}
-	}
-	if den == 0 {
-		return 0
+// RecommendItemBased:
+// - ctx: cancelación/deadline; al cancelar devuelve los mejores items puntuados hasta ese momento y ctx.Err()
+// - ds: dataset ya cargado
+// - user: userId objetivo
+// - topK: cuántas recomendaciones devolver
+// - metric: similitud a usar
+// - neighborK: cuántos vecinos por candidato considerar (si 0 -> usar todos los items que user calificó)
+// Entrena un ItemKNN desechable; para llamadas repetidas usar NewItemKNN + Fit.
+func RecommendItemBased(ctx context.Context, ds *Dataset, user int, topK int, metric SimMetric, neighborK int) ([]ItemScore, error) {
+	m := NewItemKNN(metric, neighborK)
+	if err := m.Fit(ds); err != nil {
+		return nil, err
 	}
-	return num / den
+	return m.Recommend(ctx, user, RecommendOptions{TopK: topK})
 }
 
-func RecommendItemBasedParallel(ds *Dataset, user int, topK int, metric SimMetric, neighborK int, workers int) []ItemScore {
-	userRatings, ok := ds.UserRatings[user]
-	if !ok {
-		return nil
+// RecommendItemBasedParallel: igual que RecommendItemBased pero puntuando los
+// candidatos con `workers` goroutines, que se detienen al cancelar ctx
+func RecommendItemBasedParallel(ctx context.Context, ds *Dataset, user int, topK int, metric SimMetric, neighborK int, workers int) ([]ItemScore, error) {
+	m := NewItemKNN(metric, neighborK)
+	m.Workers = workers
+	if err := m.Fit(ds); err != nil {
+		return nil, err
 	}
+	return m.Recommend(ctx, user, RecommendOptions{TopK: topK})
+}
 
-	itemIndex := BuildItemIndex(ds)
-
-	// candidatos
-	candidates := make([]int, 0)
-	for it := range itemIndex {
-		if _, seen := userRatings[it]; !seen {
-			candidates = append(candidates, it)
-		}
+// RecommendUserBased:
+// - predice usando los K vecinos usuarios más similares
+// - neighborK = cuántos vecinos usuarios considerar
+func RecommendUserBased(ctx context.Context, ds *Dataset, user int, topK int, metric SimMetric, neighborK int) ([]ItemScore, error) {
+	m := NewUserKNN(metric, neighborK)
+	if err := m.Fit(ds); err != nil {
+		return nil, err
 	}
+	return m.Recommend(ctx, user, RecommendOptions{TopK: topK})
+}
 
-	chunk := len(candidates) / workers
-	if chunk == 0 {
-		chunk = 1
+// ----------------- util -----------------
+func abs(a float64) float64 {
+	if a < 0 {
+		return -a
 	}
+	return a
+}
 
-	out := make(chan map[int]float64, workers)
-
-	for w := 0; w < workers; w++ {
-		start := w * chunk
-		end := start + chunk
-		if end > len(candidates) {
-			end = len(candidates)
-		}
-
-		go func(slice []int) {
-			partial := make(map[int]float64)
-			for _, itemV := range slice {
-				// *** igual que la lógica normal que ya tienes ***
-				partial[itemV] = scoreItem(ds, userRatings, itemIndex, itemV, metric, neighborK)
-			}
-			out <- partial
-		}(candidates[start:end])
+// clamp01: recorta una predicción a la escala normalizada de ratings
+func clamp01(x float64) float64 {
+	if x < 0 {
+		return 0
 	}
-
-	// merge
-	scores := make(map[int]float64)
-	for i := 0; i < workers; i++ {
-		part := <-out
-		for k, v := range part {
-			scores[k] = v
-		}
+	if x > 1 {
+		return 1
 	}
-
-	return topKFromMap(scores, topK)
+	return x
 }
@@ -0,0 +1,200 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// ----------------- Re-ranking -----------------
+
+// Reranker: etapa posterior al scoring que reordena y recorta la lista de
+// cualquier recomendador. Los Score de salida son los originales; sólo cambia el orden.
+type Reranker interface {
+	Name() string
+	Rerank(user int, items []ItemScore, k int) []ItemScore
+}
+
+// ItemSimilarity: similitud entre dos películas
+type ItemSimilarity func(a, b int) float64
+
+// NewRatingSimilarity: similitud item-item según los ratings (mismas métricas que ItemKNN)
+func NewRatingSimilarity(ds *Dataset, metric SimMetric) ItemSimilarity {
+	index := BuildItemIndex(ds)
+	vecs := make(map[int]sparseVec, len(index))
+	for it, users := range index {
+		vecs[it] = toSparse(users)
+	}
+	return func(a, b int) float64 {
+		return simBetweenSparse(vecs[a], vecs[b], metric)
+	}
+}
+
+// GenreSimilarity: Jaccard entre los géneros de dos películas del catálogo
+func GenreSimilarity(cat *Catalog) ItemSimilarity {
+	return func(a, b int) float64 {
+		ga, gb := cat.Genres(a), cat.Genres(b)
+		if len(ga) == 0 && len(gb) == 0 {
+			return 0
+		}
+		inter := 0
+		for _, x := range ga {
+			for _, y := range gb {
+				if x == y {
+					inter++
+					break
+				}
+			}
+		}
+		return float64(inter) / float64(len(ga)+len(gb)-inter)
+	}
+}
+
+// MMR: Maximal Marginal Relevance con restricciones opcionales de géneros.
+// En cada paso elige el candidato que maximiza
+//
+//	Lambda*relevancia - (1-Lambda)*max sim(candidato, ya elegidos)
+//
+// con la relevancia normalizada a [0,1]. Lambda=1 deja el orden original.
+type MMR struct {
+	Sim    ItemSimilarity
+	Lambda float64
+
+	// restricciones de cobertura (requieren Catalog)
+	Catalog     *Catalog
+	MaxPerGenre int // máximo de películas por género en la lista (0 = sin límite)
+	MinGenres   int // géneros distintos que la lista debe cubrir si hay candidatos
+}
+
+func (r *MMR) Name() string {
+	name := fmt.Sprintf("mmr(λ=%.2f", r.Lambda)
+	if r.MaxPerGenre > 0 {
+		name += fmt.Sprintf(",max/genre=%d", r.MaxPerGenre)
+	}
+	if r.MinGenres > 0 {
+		name += fmt.Sprintf(",min genres=%d", r.MinGenres)
+	}
+	return name + ")"
+}
+
+func (r *MMR) Rerank(user int, items []ItemScore, k int) []ItemScore {
+	if k > len(items) {
+		k = len(items)
+	}
+	rel := normalizeScores(items)
+	used := make([]bool, len(items))
+	perGenre := make(map[string]int)
+	out := make([]ItemScore, 0, k)
+
+	// fits: respeta MaxPerGenre; addsGenre: cubre un género todavía ausente
+	fits := func(id int) bool {
+		if r.Catalog == nil || r.MaxPerGenre <= 0 {
+			return true
+		}
+		for _, g := range r.Catalog.Genres(id) {
+			if perGenre[g] >= r.MaxPerGenre {
+				return false
+			}
+		}
+		return true
+	}
+	addsGenre := func(id int) bool {
+		for _, g := range r.Catalog.Genres(id) {
+			if perGenre[g] == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	for len(out) < k {
+		// si quedan tantos lugares como géneros faltan, sólo valen candidatos con género nuevo
+		needGenre := false
+		if r.Catalog != nil && r.MinGenres > 0 {
+			needGenre = r.MinGenres-len(perGenre) >= k-len(out)
+		}
+
+		best, bestVal := -1, math.Inf(-1)
+		relaxed, relaxedVal := -1, math.Inf(-1) // mejor candidato ignorando restricciones
+		for i, it := range items {
+			if used[i] {
+				continue
+			}
+			maxSim := 0.0
+			if r.Sim != nil {
+				for _, sel := range out {
+					maxSim = max(maxSim, r.Sim(it.MovieID, sel.MovieID))
+				}
+			}
+			val := r.Lambda*rel[it.MovieID] - (1-r.Lambda)*maxSim
+			if val > relaxedVal {
+				relaxed, relaxedVal = i, val
+			}
+			if !fits(it.MovieID) || (needGenre && !addsGenre(it.MovieID)) {
+				continue
+			}
+			if val > bestVal {
+				best, bestVal = i, val
+			}
+		}
+		if best < 0 {
+			// ningún candidato cumple las restricciones: completar igual
+			best = relaxed
+		}
+		used[best] = true
+		out = append(out, items[best])
+		if r.Catalog != nil {
+			for _, g := range r.Catalog.Genres(items[best].MovieID) {
+				perGenre[g]++
+			}
+		}
+	}
+	return out
+}
+
+// Reranked: envuelve un Recommender y aplica un Reranker sobre un pool de
+// TopK*PoolFactor candidatos
+type Reranked struct {
+	Rec        Recommender
+	Reranker   Reranker
+	PoolFactor int // 0 -> 5
+}
+
+// WithReranker aplica rr a la salida de rec
+func WithReranker(rec Recommender, rr Reranker) *Reranked {
+	return &Reranked{Rec: rec, Reranker: rr}
+}
+
+func (r *Reranked) Name() string {
+	return r.Rec.Name() + "+" + r.Reranker.Name()
+}
+
+// Fit entrena el recomendador y, si el re-ranker también necesita datos (p.ej.
+// la popularidad de cada item), lo entrena con el mismo dataset
+func (r *Reranked) Fit(ds *Dataset) error {
+	if err := r.Rec.Fit(ds); err != nil {
+		return err
+	}
+	if f, ok := r.Reranker.(interface{ Fit(*Dataset) error }); ok {
+		return f.Fit(ds)
+	}
+	return nil
+}
+
+func (r *Reranked) Predict(user, item int) (float64, bool) {
+	return r.Rec.Predict(user, item)
+}
+
+func (r *Reranked) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	f := r.PoolFactor
+	if f <= 0 {
+		f = defaultPoolFactor
+	}
+	pool := opts
+	pool.TopK = opts.TopK * f
+	items, err := r.Rec.Recommend(ctx, user, pool)
+	if err != nil {
+		return truncate(items, opts.TopK), err
+	}
+	return r.Reranker.Rerank(user, items, opts.TopK), nil
+}
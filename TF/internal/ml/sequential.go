@@ -0,0 +1,366 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ----------------- Recomendación secuencial (siguiente película) -----------------
+
+// ErrNoTimestamps: el modelo necesita el orden de los ratings y el dataset no lo trae
+var ErrNoTimestamps = errors.New("ml: el dataset no tiene timestamps")
+
+// SequentialRecommender: modelos que, dadas las últimas películas vistas (de la
+// más antigua a la más reciente), predicen cuáles vienen después
+type SequentialRecommender interface {
+	Name() string
+	Fit(ds *Dataset) error
+	NextItems(ctx context.Context, last []int, k int) ([]ItemScore, error)
+}
+
+// defaultHistory: cuántas de las últimas películas del usuario usa Recommend
+const defaultHistory = 5
+
+// lastN: las últimas n películas de la secuencia del usuario (n <= 0 -> defaultHistory)
+func lastN(seq []int, n int) []int {
+	if n <= 0 {
+		n = defaultHistory
+	}
+	if len(seq) > n {
+		return seq[len(seq)-n:]
+	}
+	return seq
+}
+
+// ----------------- Markov de primer orden -----------------
+
+// Markov: cadena de Markov de primer orden sobre la secuencia de cada usuario.
+//
+//	P(j|i) = (c(i,j) + Smoothing) / (c(i) + Smoothing*|items|)
+//
+// Con varias películas de historia mezcla las transiciones desde cada una, con
+// peso 1/2 por cada paso hacia atrás (la más reciente pesa 1).
+// También implementa Recommender: Recommend usa las últimas History películas
+// del usuario y Predict devuelve P(item|historia), una afinidad y no un rating.
+type Markov struct {
+	Smoothing float64 // suavizado aditivo (Laplace = 1)
+	History   int     // películas de historia para Recommend/Predict (0 -> 5)
+
+	ds    *Dataset
+	items []int                   // vocabulario ordenado
+	trans map[int]map[int]float64 // i -> (j -> c(i,j))
+	outs  map[int]float64         // i -> c(i)
+	seqs  map[int][]int           // user -> secuencia ordenada
+}
+
+// NewMarkov crea una cadena de Markov sin entrenar
+func NewMarkov(smoothing float64) *Markov {
+	return &Markov{Smoothing: smoothing}
+}
+
+func (m *Markov) Name() string {
+	return fmt.Sprintf("markov(a=%g)", m.Smoothing)
+}
+
+func (m *Markov) Fit(ds *Dataset) error {
+	if len(ds.Timestamps) == 0 {
+		return ErrNoTimestamps
+	}
+	trans := make(map[int]map[int]float64)
+	outs := make(map[int]float64)
+	seqs := make(map[int][]int, len(ds.Timestamps))
+	for u := range ds.Timestamps {
+		seq := ds.Sequence(u)
+		seqs[u] = seq
+		for k := 1; k < len(seq); k++ {
+			i, j := seq[k-1], seq[k]
+			if trans[i] == nil {
+				trans[i] = make(map[int]float64)
+			}
+			trans[i][j]++
+			outs[i]++
+		}
+	}
+	m.ds = ds
+	m.items = sortedItems(ds)
+	m.trans = trans
+	m.outs = outs
+	m.seqs = seqs
+	return nil
+}
+
+// prob: P(j|i) suavizada
+func (m *Markov) prob(i, j int) float64 {
+	den := m.outs[i] + m.Smoothing*float64(len(m.items))
+	if den == 0 {
+		return 0
+	}
+	return (m.trans[i][j] + m.Smoothing) / den
+}
+
+// score: mezcla de P(item|x) para cada x de la historia
+func (m *Markov) score(last []int, item int) float64 {
+	sc, w, total := 0.0, 1.0, 0.0
+	for k := len(last) - 1; k >= 0; k-- {
+		sc += w * m.prob(last[k], item)
+		total += w
+		w /= 2
+	}
+	if total == 0 {
+		return 0
+	}
+	return sc / total
+}
+
+func (m *Markov) NextItems(ctx context.Context, last []int, k int) ([]ItemScore, error) {
+	return m.next(ctx, last, k, nil)
+}
+
+// next: top-k de todo el vocabulario menos la historia; allow (nil = todos)
+// restringe además los candidatos
+func (m *Markov) next(ctx context.Context, last []int, k int, allow func(item int) bool) ([]ItemScore, error) {
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	if len(last) == 0 {
+		return nil, nil
+	}
+	inHistory := make(map[int]bool, len(last))
+	for _, it := range last {
+		inHistory[it] = true
+	}
+	top := newTopKHeap(k)
+	for n, it := range m.items {
+		if n%256 == 0 && ctx.Err() != nil {
+			return top.sorted(), ctx.Err()
+		}
+		if inHistory[it] || (allow != nil && !allow(it)) {
+			continue
+		}
+		top.offer(ItemScore{MovieID: it, Score: m.score(last, it)})
+	}
+	return top.sorted(), nil
+}
+
+func (m *Markov) Predict(user, item int) (float64, bool) {
+	if m.ds == nil {
+		return 0, false
+	}
+	last := lastN(m.seqs[user], m.History)
+	if len(last) == 0 {
+		return 0, false
+	}
+	return m.score(last, item), true
+}
+
+func (m *Markov) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	seen, _ := opts.ratingsFor(m.ds, user)
+	return m.next(ctx, lastN(m.seqs[user], m.History), opts.TopK, func(item int) bool {
+		return opts.isCandidate(user, item, seen)
+	})
+}
+
+// ----------------- Session-kNN -----------------
+
+// defaultSessionGap: pausa entre ratings que corta una sesión
+const defaultSessionGap = time.Hour
+
+// SessionKNN: parte la secuencia de cada usuario en sesiones (pausas mayores a
+// SessionGap) y, dada la historia reciente, busca las K sesiones pasadas más
+// parecidas (coseno sobre conjuntos de películas). Cada película de esas
+// sesiones suma la similitud de la sesión donde aparece.
+type SessionKNN struct {
+	K          int           // sesiones vecinas
+	SessionGap time.Duration // 0 -> 1 hora
+	History    int           // películas de historia para Recommend/Predict (0 -> 5)
+
+	ds       *Dataset
+	sessions [][]int       // películas de cada sesión (sin repetidos, ordenadas por id)
+	byItem   map[int][]int // item -> índices de las sesiones donde aparece
+	seqs     map[int][]int
+}
+
+// NewSessionKNN crea un session-kNN sin entrenar
+func NewSessionKNN(k int) *SessionKNN {
+	return &SessionKNN{K: k}
+}
+
+func (m *SessionKNN) Name() string {
+	return fmt.Sprintf("session-knn(k=%d)", m.K)
+}
+
+func (m *SessionKNN) Fit(ds *Dataset) error {
+	if len(ds.Timestamps) == 0 {
+		return ErrNoTimestamps
+	}
+	gap := int64(m.SessionGap / time.Second)
+	if gap <= 0 {
+		gap = int64(defaultSessionGap / time.Second)
+	}
+
+	var sessions [][]int
+	byItem := make(map[int][]int)
+	seqs := make(map[int][]int, len(ds.Timestamps))
+	for _, u := range ds.sortedUsers() {
+		seq := ds.Sequence(u)
+		if len(seq) == 0 {
+			continue
+		}
+		seqs[u] = seq
+		start := 0
+		for k := 1; k <= len(seq); k++ {
+			if k < len(seq) && ds.Timestamp(u, seq[k])-ds.Timestamp(u, seq[k-1]) <= gap {
+				continue
+			}
+			s := append([]int(nil), seq[start:k]...)
+			sort.Ints(s)
+			for _, it := range s {
+				byItem[it] = append(byItem[it], len(sessions))
+			}
+			sessions = append(sessions, s)
+			start = k
+		}
+	}
+	m.ds = ds
+	m.sessions = sessions
+	m.byItem = byItem
+	m.seqs = seqs
+	return nil
+}
+
+// neighborSessions: las K sesiones con mayor |q ∩ s| / sqrt(|q|*|s|)
+func (m *SessionKNN) neighborSessions(ctx context.Context, query map[int]bool) ([]neighbor, error) {
+	overlap := make(map[int]float64)
+	for it := range query {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, s := range m.byItem[it] {
+			overlap[s]++
+		}
+	}
+	for s, n := range overlap {
+		overlap[s] = n / math.Sqrt(float64(len(query)*len(m.sessions[s])))
+	}
+	if m.K > 0 {
+		return topNneighborsFromScores(overlap, m.K), nil
+	}
+	neighbors := make([]neighbor, 0, len(overlap))
+	for s, sc := range overlap {
+		neighbors = append(neighbors, neighbor{id: s, score: sc})
+	}
+	sortNeighbors(neighbors)
+	return neighbors, nil
+}
+
+// scores: item -> suma de similitudes de las sesiones vecinas que lo contienen
+func (m *SessionKNN) scores(ctx context.Context, last []int) (map[int]float64, error) {
+	query := make(map[int]bool, len(last))
+	for _, it := range last {
+		query[it] = true
+	}
+	neighbors, err := m.neighborSessions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int]float64)
+	for _, nb := range neighbors {
+		for _, it := range m.sessions[nb.id] {
+			if !query[it] {
+				scores[it] += nb.score
+			}
+		}
+	}
+	return scores, nil
+}
+
+func (m *SessionKNN) NextItems(ctx context.Context, last []int, k int) ([]ItemScore, error) {
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	scores, err := m.scores(ctx, last)
+	if err != nil {
+		return nil, err
+	}
+	return topKFromMap(scores, k), nil
+}
+
+func (m *SessionKNN) Predict(user, item int) (float64, bool) {
+	if m.ds == nil {
+		return 0, false
+	}
+	scores, _ := m.scores(context.Background(), lastN(m.seqs[user], m.History))
+	sc, ok := scores[item]
+	return sc, ok
+}
+
+func (m *SessionKNN) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	scores, err := m.scores(ctx, lastN(m.seqs[user], m.History))
+	if err != nil {
+		return nil, err
+	}
+	seen, _ := opts.ratingsFor(m.ds, user)
+	for it := range scores {
+		if !opts.isCandidate(user, it, seen) {
+			delete(scores, it)
+		}
+	}
+	return topKFromMap(scores, opts.TopK), nil
+}
+
+// ----------------- evaluación leave-last-out -----------------
+
+// LeaveLastOutResult: métricas de EvaluateLeaveLastOut
+type LeaveLastOutResult struct {
+	Model   string  `json:"model"`
+	Users   int     `json:"users"`    // usuarios evaluados
+	K       int     `json:"k"`        // largo de la lista
+	HitRate float64 `json:"hit_rate"` // fracción con la película real en el top-K
+	MRR     float64 `json:"mrr"`      // media de 1/posición (0 si no aparece)
+}
+
+// EvaluateLeaveLastOut entrena rec sobre train y, para cada usuario de test
+// (ver Dataset.LeaveLastOut), le pide k películas a partir de sus últimas history
+// películas de train y busca la que realmente vio después
+func EvaluateLeaveLastOut(ctx context.Context, rec SequentialRecommender, train, test *Dataset, history, k int) (LeaveLastOutResult, error) {
+	res := LeaveLastOutResult{Model: rec.Name(), K: k}
+	if err := rec.Fit(train); err != nil {
+		return res, err
+	}
+	hits, rr := 0.0, 0.0
+	for _, u := range test.sortedUsers() {
+		last := lastN(train.Sequence(u), history)
+		if len(last) == 0 {
+			continue
+		}
+		recs, err := rec.NextItems(ctx, last, k)
+		if err != nil {
+			return res, err
+		}
+		res.Users++
+		for target := range test.UserRatings[u] {
+			for pos, it := range recs {
+				if it.MovieID == target {
+					hits++
+					rr += 1 / float64(pos+1)
+					break
+				}
+			}
+		}
+	}
+	if res.Users > 0 {
+		res.HitRate = hits / float64(res.Users)
+		res.MRR = rr / float64(res.Users)
+	}
+	return res, nil
+}
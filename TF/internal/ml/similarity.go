@@ -1,7 +1,9 @@
 package ml
 
 import (
+	"fmt"
 	"math"
+	"sort"
 )
 
 // Cosine similarity between two sparse vectors (maps). Uses all keys present in either map.
@@ -89,3 +91,221 @@ func Jaccard(a, b map[int]float64) float64 {
 	}
 	return float64(inter) / float64(len(union))
 }
+
+// ----------------- vectores dispersos ordenados -----------------
+
+// sparseVec: vector disperso con ids ordenados. Las similitudes sobre sparseVec
+// recorren ambos vectores con un merge (sin hashing) y siempre en el mismo orden,
+// por lo que el resultado es bit a bit reproducible entre llamadas.
+// w (opcional) trae un peso por componente, p.ej. el decaimiento temporal de cada
+// rating; nil equivale a todos los pesos en 1.
+type sparseVec struct {
+	ids  []int
+	vals []float64
+	w    []float64
+	norm float64 // sqrt(sum (w*v)^2), precalculada para coseno
+}
+
+func toSparse(m map[int]float64) sparseVec {
+	return toSparseWeighted(m, nil)
+}
+
+// toSparseWeighted: como toSparse, con pesos por clave (nil = sin pesos)
+func toSparseWeighted(m map[int]float64, weight func(k int) float64) sparseVec {
+	v := sparseVec{ids: make([]int, 0, len(m)), vals: make([]float64, len(m))}
+	if weight != nil {
+		v.w = make([]float64, len(m))
+	}
+	for k := range m {
+		v.ids = append(v.ids, k)
+	}
+	sort.Ints(v.ids)
+	sum := 0.0
+	for i, k := range v.ids {
+		v.vals[i] = m[k]
+		x := m[k]
+		if weight != nil {
+			v.w[i] = weight(k)
+			x *= v.w[i]
+		}
+		sum += x * x
+	}
+	v.norm = math.Sqrt(sum)
+	return v
+}
+
+func (v sparseVec) weight(i int) float64 {
+	if v.w == nil {
+		return 1
+	}
+	return v.w[i]
+}
+
+// cosineSparse: mismo valor que Cosine sobre los maps equivalentes; con pesos,
+// coseno entre los vectores escalados componente a componente
+func cosineSparse(a, b sparseVec) float64 {
+	if a.norm == 0 || b.norm == 0 {
+		return 0
+	}
+	dot := 0.0
+	for i, j := 0, 0; i < len(a.ids) && j < len(b.ids); {
+		switch {
+		case a.ids[i] < b.ids[j]:
+			i++
+		case a.ids[i] > b.ids[j]:
+			j++
+		default:
+			dot += a.vals[i] * a.weight(i) * b.vals[j] * b.weight(j)
+			i++
+			j++
+		}
+	}
+	return dot / (a.norm * b.norm)
+}
+
+// pearsonSparse: mismo valor que Pearson (sólo claves comunes, mínimo 2); con
+// pesos, Pearson ponderado donde cada clave común pesa wa*wb
+func pearsonSparse(a, b sparseVec) float64 {
+	var common int
+	var sumW, sumA, sumB float64
+	for i, j := 0, 0; i < len(a.ids) && j < len(b.ids); {
+		switch {
+		case a.ids[i] < b.ids[j]:
+			i++
+		case a.ids[i] > b.ids[j]:
+			j++
+		default:
+			pw := a.weight(i) * b.weight(j)
+			common++
+			sumW += pw
+			sumA += pw * a.vals[i]
+			sumB += pw * b.vals[j]
+			i++
+			j++
+		}
+	}
+	if common < 2 || sumW == 0 {
+		return 0
+	}
+	meanA := sumA / sumW
+	meanB := sumB / sumW
+
+	var num, denA, denB float64
+	for i, j := 0, 0; i < len(a.ids) && j < len(b.ids); {
+		switch {
+		case a.ids[i] < b.ids[j]:
+			i++
+		case a.ids[i] > b.ids[j]:
+			j++
+		default:
+			pw := a.weight(i) * b.weight(j)
+			da := a.vals[i] - meanA
+			db := b.vals[j] - meanB
+			num += pw * da * db
+			denA += pw * da * da
+			denB += pw * db * db
+			i++
+			j++
+		}
+	}
+	if denA == 0 || denB == 0 {
+		return 0
+	}
+	return num / (math.Sqrt(denA) * math.Sqrt(denB))
+}
+
+// jaccardSparse: mismo valor que Jaccard; con pesos, Jaccard ponderado
+// (sum min / sum max sobre la unión)
+func jaccardSparse(a, b sparseVec) float64 {
+	inter, totalA, totalB := 0.0, 0.0, 0.0
+	for i := range a.ids {
+		totalA += a.weight(i)
+	}
+	for j := range b.ids {
+		totalB += b.weight(j)
+	}
+	for i, j := 0, 0; i < len(a.ids) && j < len(b.ids); {
+		switch {
+		case a.ids[i] < b.ids[j]:
+			i++
+		case a.ids[i] > b.ids[j]:
+			j++
+		default:
+			inter += min(a.weight(i), b.weight(j))
+			i++
+			j++
+		}
+	}
+	union := totalA + totalB - inter
+	if union == 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// simBetweenSparse: dispatch a la similitud de metric (default coseno)
+func simBetweenSparse(a, b sparseVec, metric SimMetric) float64 {
+	switch metric {
+	case CosineSim:
+		return cosineSparse(a, b)
+	case PearsonSim:
+		return pearsonSparse(a, b)
+	case JaccardSim:
+		return jaccardSparse(a, b)
+	default:
+		return cosineSparse(a, b)
+	}
+}
+
+// commonCount: claves presentes en ambos vectores (co-calificaciones)
+func commonCount(a, b sparseVec) int {
+	n := 0
+	for i, j := 0, 0; i < len(a.ids) && j < len(b.ids); {
+		switch {
+		case a.ids[i] < b.ids[j]:
+			i++
+		case a.ids[i] > b.ids[j]:
+			j++
+		default:
+			n++
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// simConfig: métrica de similitud más los ajustes por cantidad de co-calificaciones
+// que usan los modelos KNN
+type simConfig struct {
+	metric     SimMetric
+	minSupport int     // co-calificaciones mínimas para que la similitud cuente (0 = sin mínimo)
+	shrinkage  float64 // sim * n/(n+shrinkage): castiga similitudes con poco soporte (0 = sin shrinkage)
+}
+
+func (c simConfig) sim(a, b sparseVec) float64 {
+	s := simBetweenSparse(a, b, c.metric)
+	if s == 0 || (c.minSupport <= 0 && c.shrinkage <= 0) {
+		return s
+	}
+	n := commonCount(a, b)
+	if n < c.minSupport {
+		return 0
+	}
+	if c.shrinkage > 0 {
+		s *= float64(n) / (float64(n) + c.shrinkage)
+	}
+	return s
+}
+
+// suffix: parámetros no triviales para Name() de los modelos
+func (c simConfig) suffix() string {
+	s := ""
+	if c.minSupport > 0 {
+		s += fmt.Sprintf(",minsup=%d", c.minSupport)
+	}
+	if c.shrinkage > 0 {
+		s += fmt.Sprintf(",shrink=%g", c.shrinkage)
+	}
+	return s
+}
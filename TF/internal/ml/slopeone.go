@@ -0,0 +1,386 @@
+package ml
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ----------------- Slope One -----------------
+
+// SlopeOneVariant: variante de Slope One
+type SlopeOneVariant int
+
+const (
+	WeightedSlopeOne SlopeOneVariant = iota
+	// BiPolarSlopeOne separa los pares de items que le gustaron al usuario
+	// (rating > su media) de los que no, y predice con cada tabla por separado
+	BiPolarSlopeOne
+)
+
+// devStat: suma de diferencias r(i) - r(j) y cuántos usuarios calificaron ambos
+type devStat struct {
+	sum   float64
+	count int
+}
+
+// devTable: desviaciones por par de items, guardadas sólo con i < j
+type devTable map[int]map[int]devStat
+
+// add suma sign * (ri - rj) al par (i,j)
+func (t devTable) add(i, j int, ri, rj float64, sign int) {
+	if i > j {
+		i, j = j, i
+		ri, rj = rj, ri
+	}
+	row, ok := t[i]
+	if !ok {
+		row = make(map[int]devStat)
+		t[i] = row
+	}
+	st := row[j]
+	st.sum += float64(sign) * (ri - rj)
+	st.count += sign
+	if st.count <= 0 {
+		delete(row, j)
+		if len(row) == 0 {
+			delete(t, i)
+		}
+		return
+	}
+	row[j] = st
+}
+
+// get devuelve la desviación media de i respecto a j y el soporte del par
+func (t devTable) get(i, j int) (float64, int) {
+	if i < j {
+		st := t[i][j]
+		if st.count == 0 {
+			return 0, 0
+		}
+		return st.sum / float64(st.count), st.count
+	}
+	st := t[j][i]
+	if st.count == 0 {
+		return 0, 0
+	}
+	return -st.sum / float64(st.count), st.count
+}
+
+// merge suma los pares de o en t
+func (t devTable) merge(o devTable) {
+	for i, row := range o {
+		dst, ok := t[i]
+		if !ok {
+			t[i] = row
+			continue
+		}
+		for j, st := range row {
+			cur := dst[j]
+			cur.sum += st.sum
+			cur.count += st.count
+			dst[j] = cur
+		}
+	}
+}
+
+// SlopeOne: Weighted / Bi-Polar Slope One sobre ml.Dataset.
+// La tabla de desviaciones se construye en paralelo en Fit y se mantiene al día
+// con AddRating sin re-entrenar. Los ratings agregados así quedan en una copia
+// propia del modelo: el dataset de Fit (compartido con otros modelos) no se toca.
+type SlopeOne struct {
+	Variant SlopeOneVariant
+	Workers int // goroutines para Fit y Recommend (0 -> runtime.NumCPU())
+
+	mu        sync.RWMutex
+	ds        *Dataset
+	own       *Dataset    // usuarios con ratings agregados por AddRating (copia completa de cada uno)
+	all       devTable    // Weighted: todos los pares
+	like      devTable    // Bi-Polar: pares que al usuario le gustaron
+	dislike   devTable    // Bi-Polar: pares que al usuario no le gustaron
+	items     map[int]int // item -> cuántos usuarios lo calificaron
+	userMeans map[int]float64
+	sum       float64 // suma y cantidad de todos los ratings, para la media global
+	n         int
+}
+
+// NewSlopeOne crea un modelo Slope One sin entrenar
+func NewSlopeOne(variant SlopeOneVariant) *SlopeOne {
+	return &SlopeOne{Variant: variant}
+}
+
+func (m *SlopeOne) Name() string {
+	if m.Variant == BiPolarSlopeOne {
+		return "slope-one(bi-polar)"
+	}
+	return "slope-one(weighted)"
+}
+
+func (m *SlopeOne) workers() int {
+	if m.Workers > 0 {
+		return m.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// Fit construye las tablas de desviaciones repartiendo los usuarios entre
+// workers; cada uno acumula tablas parciales que luego se mezclan
+func (m *SlopeOne) Fit(ds *Dataset) error {
+	workers := m.workers()
+
+	type partial struct {
+		all, like, dislike devTable
+	}
+
+	users := make(chan int, workers*16)
+	out := make(chan partial, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := partial{all: devTable{}, like: devTable{}, dislike: devTable{}}
+			for u := range users {
+				ratings := ds.UserRatings[u]
+				accumulatePairs(m.Variant, p.all, p.like, p.dislike, ratings, meanOf(ratings), 1)
+			}
+			out <- p
+		}()
+	}
+
+	go func() {
+		for u := range ds.UserRatings {
+			users <- u
+		}
+		close(users)
+		wg.Wait()
+		close(out)
+	}()
+
+	all, like, dislike := devTable{}, devTable{}, devTable{}
+	for p := range out {
+		all.merge(p.all)
+		like.merge(p.like)
+		dislike.merge(p.dislike)
+	}
+
+	items := make(map[int]int)
+	means := make(map[int]float64, len(ds.UserRatings))
+	sum, n := 0.0, 0
+	for u, ratings := range ds.UserRatings {
+		means[u] = meanOf(ratings)
+		for it, r := range ratings {
+			items[it]++
+			sum += r
+			n++
+		}
+	}
+
+	m.mu.Lock()
+	m.ds = ds
+	m.own = &Dataset{UserRatings: make(map[int]map[int]float64)}
+	m.sum, m.n = sum, n
+	m.all, m.like, m.dislike = all, like, dislike
+	m.items = items
+	m.userMeans = means
+	m.mu.Unlock()
+	return nil
+}
+
+// accumulatePairs suma (sign=1) o resta (sign=-1) la contribución de un usuario
+func accumulatePairs(variant SlopeOneVariant, all, like, dislike devTable, ratings map[int]float64, mean float64, sign int) {
+	for i, ri := range ratings {
+		for j, rj := range ratings {
+			if i >= j {
+				continue
+			}
+			if variant == BiPolarSlopeOne {
+				switch {
+				case ri > mean && rj > mean:
+					like.add(i, j, ri, rj, sign)
+				case ri < mean && rj < mean:
+					dislike.add(i, j, ri, rj, sign)
+				}
+				continue
+			}
+			all.add(i, j, ri, rj, sign)
+		}
+	}
+}
+
+// AddRating agrega (o reemplaza) un rating normalizado sin re-entrenar y
+// actualiza las tablas de forma incremental: O(#items del usuario) para Weighted;
+// Bi-Polar recalcula sólo la contribución de ese usuario porque cambia su media.
+func (m *SlopeOne) AddRating(user, item int, rating float64) error {
+	return m.AddRatingAt(user, item, rating, 0)
+}
+
+// AddRatingAt: AddRating registrando el momento (unix) del rating (0 = desconocido)
+func (m *SlopeOne) AddRatingAt(user, item int, rating float64, ts int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ds == nil {
+		return ErrNotFitted
+	}
+
+	ratings := m.ratingsOf(user)
+	old, had := ratings[item]
+
+	// la primera vez que cambia un usuario se copian sus ratings (con timestamps)
+	if _, ok := m.own.UserRatings[user]; !ok {
+		for it := range m.ds.UserRatings[user] {
+			m.own.copyRating(m.ds, user, it)
+		}
+	}
+	add := func() {
+		if ts != 0 {
+			m.own.AddRatingAt(user, item, rating, ts)
+		} else {
+			m.own.AddRating(user, item, rating)
+		}
+	}
+
+	if m.Variant == BiPolarSlopeOne {
+		accumulatePairs(m.Variant, m.all, m.like, m.dislike, ratings, m.userMeans[user], -1)
+		add()
+		ratings = m.own.UserRatings[user]
+		m.userMeans[user] = meanOf(ratings)
+		accumulatePairs(m.Variant, m.all, m.like, m.dislike, ratings, m.userMeans[user], 1)
+	} else {
+		for j, rj := range ratings {
+			if j == item {
+				continue
+			}
+			if had {
+				m.all.add(item, j, old, rj, -1)
+			}
+			m.all.add(item, j, rating, rj, 1)
+		}
+		add()
+		m.userMeans[user] = meanOf(m.own.UserRatings[user])
+	}
+
+	if had {
+		m.sum += rating - old
+	} else {
+		m.items[item]++
+		m.sum += rating
+		m.n++
+	}
+	return nil
+}
+
+// ratingsOf: ratings actuales del usuario (los agregados por AddRating tienen
+// prioridad sobre el dataset de Fit). Requiere m.mu tomado.
+func (m *SlopeOne) ratingsOf(user int) map[int]float64 {
+	if r, ok := m.own.UserRatings[user]; ok {
+		return r
+	}
+	return m.ds.UserRatings[user]
+}
+
+// globalMean: media de todos los ratings (baseline de usuarios desconocidos).
+// Requiere m.mu tomado.
+func (m *SlopeOne) globalMean() float64 {
+	if m.n == 0 {
+		return 0
+	}
+	return m.sum / float64(m.n)
+}
+
+// predict: Slope One ponderado por soporte; ok=false sin pares con item.
+// Requiere m.mu tomado (lectura).
+func (m *SlopeOne) predict(ratings map[int]float64, mean float64, item int) (float64, bool) {
+	num := 0.0
+	den := 0
+	for i, ri := range ratings {
+		if i == item {
+			continue
+		}
+		if m.Variant == BiPolarSlopeOne {
+			var t devTable
+			switch {
+			case ri > mean:
+				t = m.like
+			case ri < mean:
+				t = m.dislike
+			default:
+				continue
+			}
+			if dev, c := t.get(item, i); c > 0 {
+				num += (dev + ri) * float64(c)
+				den += c
+			}
+			continue
+		}
+		if dev, c := m.all.get(item, i); c > 0 {
+			num += (dev + ri) * float64(c)
+			den += c
+		}
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return clamp01(num / float64(den)), true
+}
+
+func (m *SlopeOne) Predict(user, item int) (float64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.ds == nil {
+		return 0, false
+	}
+	ratings := m.ratingsOf(user)
+	if len(ratings) == 0 {
+		return m.globalMean(), false
+	}
+	score, ok := m.predict(ratings, m.userMeans[user], item)
+	if !ok {
+		return m.userMeans[user], false
+	}
+	return score, true
+}
+
+func (m *SlopeOne) Recommend(ctx context.Context, user int, opts RecommendOptions) ([]ItemScore, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.ds == nil {
+		return nil, ErrNotFitted
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ratings, ok := opts.ratingsFor(m.ds, user)
+	if opts.Profile == nil {
+		ratings = m.ratingsOf(user)
+		ok = len(ratings) > 0
+	}
+	if !ok {
+		return nil, nil
+	}
+	mean := meanOf(ratings)
+
+	candidates := make([]int, 0, len(m.items))
+	for it := range m.items {
+		if opts.isCandidate(user, it, ratings) {
+			candidates = append(candidates, it)
+		}
+	}
+
+	return scoreCandidates(ctx, candidates, opts.TopK, m.workers(), func(item int) (float64, bool) {
+		return m.predict(ratings, mean, item)
+	})
+}
+
+// meanOf: media de un conjunto de ratings (0 si está vacío)
+func meanOf(ratings map[int]float64) float64 {
+	if len(ratings) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, r := range ratings {
+		sum += r
+	}
+	return sum / float64(len(ratings))
+}
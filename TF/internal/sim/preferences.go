@@ -0,0 +1,141 @@
+package sim
+
+import (
+	"math"
+
+	"TF/internal/ml"
+)
+
+// ----------------- Preferencias ocultas -----------------
+
+// Preferences: el rating "verdadero" (normalizado 0..1) que un usuario simulado
+// le daría a cualquier película. Los modelos nunca lo ven; sólo ven los ratings
+// de lo que el usuario aceptó.
+type Preferences interface {
+	Rating(user, item int) float64
+}
+
+// Regularización de los sesgos y afinidades de HiddenPreferences
+const (
+	biasShrinkage     = 10
+	affinityShrinkage = 5
+)
+
+// HiddenPreferences: modelo de sesgos estimado sobre un dataset real,
+//
+//	r(u,i) = media + b_u + b_i + afinidad_u(géneros de i) + ruido(u,i)
+//
+// con b_u y b_i regularizados, la afinidad de género como el desvío medio del
+// usuario en las películas de ese género (si hay catálogo) y un ruido gaussiano
+// fijo por par (la misma consulta devuelve siempre lo mismo).
+type HiddenPreferences struct {
+	Mean     float64
+	UserBias map[int]float64
+	ItemBias map[int]float64
+	Affinity map[int]map[string]float64 // user -> género -> desvío
+	Catalog  *ml.Catalog                // nil -> sin afinidad de género
+	Noise    float64                    // desvío del ruido por par
+	Seed     int64
+}
+
+// NewHiddenPreferences estima las preferencias ocultas a partir de ds
+func NewHiddenPreferences(ds *ml.Dataset, cat *ml.Catalog, noise float64, seed int64) *HiddenPreferences {
+	p := &HiddenPreferences{
+		UserBias: make(map[int]float64),
+		ItemBias: make(map[int]float64),
+		Affinity: make(map[int]map[string]float64),
+		Catalog:  cat,
+		Noise:    noise,
+		Seed:     seed,
+	}
+	n := 0
+	for _, items := range ds.UserRatings {
+		for _, r := range items {
+			p.Mean += r
+			n++
+		}
+	}
+	if n == 0 {
+		return p
+	}
+	p.Mean /= float64(n)
+
+	itemSum := make(map[int]float64)
+	itemN := make(map[int]int)
+	for _, items := range ds.UserRatings {
+		for it, r := range items {
+			itemSum[it] += r - p.Mean
+			itemN[it]++
+		}
+	}
+	for it, s := range itemSum {
+		p.ItemBias[it] = s / float64(itemN[it]+biasShrinkage)
+	}
+	for u, items := range ds.UserRatings {
+		s := 0.0
+		for it, r := range items {
+			s += r - p.Mean - p.ItemBias[it]
+		}
+		p.UserBias[u] = s / float64(len(items)+biasShrinkage)
+	}
+	if cat == nil {
+		return p
+	}
+	for u, items := range ds.UserRatings {
+		sum := make(map[string]float64)
+		cnt := make(map[string]int)
+		for it, r := range items {
+			res := r - p.Mean - p.UserBias[u] - p.ItemBias[it]
+			for _, g := range cat.Genres(it) {
+				sum[g] += res
+				cnt[g]++
+			}
+		}
+		aff := make(map[string]float64, len(sum))
+		for g, s := range sum {
+			aff[g] = s / float64(cnt[g]+affinityShrinkage)
+		}
+		p.Affinity[u] = aff
+	}
+	return p
+}
+
+func (p *HiddenPreferences) Rating(user, item int) float64 {
+	r := p.Mean + p.UserBias[user] + p.ItemBias[item]
+	if p.Catalog != nil {
+		if genres := p.Catalog.Genres(item); len(genres) > 0 {
+			a := 0.0
+			for _, g := range genres {
+				a += p.Affinity[user][g]
+			}
+			r += a / float64(len(genres))
+		}
+	}
+	if p.Noise > 0 {
+		r += p.Noise * gaussian(uint64(p.Seed), uint64(user), uint64(item))
+	}
+	return min(1, max(ml.NormalizeRating(0.5), r))
+}
+
+// ----------------- azar determinista -----------------
+
+// mix: splitmix64 de varias claves
+func mix(keys ...uint64) uint64 {
+	x := uint64(0x9e3779b97f4a7c15)
+	for _, k := range keys {
+		x ^= k
+		x += 0x9e3779b97f4a7c15
+		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+		x ^= x >> 31
+	}
+	return x
+}
+
+// gaussian: normal estándar determinista para las claves (Box-Muller)
+func gaussian(keys ...uint64) float64 {
+	h := mix(keys...)
+	u1 := (float64(h>>11) + 0.5) / (1 << 53)
+	u2 := float64(mix(h)>>11) / (1 << 53)
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
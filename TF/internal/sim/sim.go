@@ -0,0 +1,288 @@
+package sim
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"TF/internal/eval"
+	"TF/internal/ml"
+)
+
+// ----------------- Simulación del ciclo recomendar -> consumir -> reentrenar -----------------
+
+// Options: parámetros de Run
+type Options struct {
+	Steps          int           // pasos de simulación (0 -> 12)
+	StepDuration   time.Duration // tiempo simulado por paso (0 -> 30 días)
+	K              int           // largo de la lista mostrada (0 -> 10)
+	ActiveFraction float64       // fracción de usuarios que entra en cada paso (0 -> 0.3)
+	// aceptación: un usuario mira la posición p con probabilidad 1/log2(p+1) y
+	// acepta con probabilidad logística en (rating oculto - Threshold)/Temperature
+	Threshold   float64     // 0 -> 0.7 (3.5 estrellas)
+	Temperature float64     // 0 -> 0.1
+	Test        *ml.Dataset // ratings reales fuera del ciclo para RMSE/MAE y ranking@K (nil -> no se evalúa)
+	Seed        int64
+	Workers     int // goroutines (<= 0 -> runtime.NumCPU())
+}
+
+func (o Options) withDefaults() Options {
+	if o.Steps <= 0 {
+		o.Steps = 12
+	}
+	if o.StepDuration <= 0 {
+		o.StepDuration = 30 * 24 * time.Hour
+	}
+	if o.K <= 0 {
+		o.K = 10
+	}
+	if o.ActiveFraction <= 0 || o.ActiveFraction > 1 {
+		o.ActiveFraction = 0.3
+	}
+	if o.Threshold == 0 {
+		o.Threshold = 0.7
+	}
+	if o.Temperature <= 0 {
+		o.Temperature = 0.1
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+	return o
+}
+
+// StepStats: qué pasó en un paso de la simulación
+type StepStats struct {
+	Step       int     `json:"step"` // 1-based
+	Day        int     `json:"day"`  // días simulados al final del paso
+	Active     int     `json:"active_users"`
+	Shown      int     `json:"shown"`
+	Accepted   int     `json:"accepted"`
+	AcceptRate float64 `json:"accept_rate"`
+	Utility    float64 `json:"utility"` // rating oculto medio de lo mostrado, en estrellas
+	// métricas sobre Options.Test del modelo entrenado al inicio del paso (ranking
+	// con corte K y relevantes los ratings >= eval.DefaultThreshold)
+	RMSE      float64 `json:"rmse,omitempty"`
+	MAE       float64 `json:"mae,omitempty"`
+	Precision float64 `json:"precision,omitempty"`
+	Recall    float64 `json:"recall,omitempty"`
+	NDCG      float64 `json:"ndcg,omitempty"`
+	Coverage  float64 `json:"coverage"`   // películas distintas mostradas / películas del dataset
+	Gini      float64 `json:"gini"`       // concentración de la exposición del paso
+	HeadShare float64 `json:"head_share"` // fracción de lo mostrado en la franja head inicial
+	DataGini  float64 `json:"data_gini"`  // concentración de los ratings del dataset al final del paso
+	Ratings   int     `json:"ratings"`    // tamaño del dataset al final del paso
+}
+
+// Result: evolución de un modelo a lo largo de la simulación
+type Result struct {
+	Model string      `json:"model"`
+	Steps []StepStats `json:"steps"`
+}
+
+// userStep: lo que hizo un usuario en un paso
+type userStep struct {
+	shown    []int
+	utility  float64
+	accepted []int
+	ratings  []float64
+}
+
+// Run parte de una copia de start y en cada paso entrena rec, le muestra K
+// recomendaciones a una fracción de los usuarios, simula cuáles aceptan según
+// prefs y agrega las aceptadas como ratings nuevos para el paso siguiente.
+// Las películas de Options.Test no se recomiendan, para no contaminar la evaluación.
+func Run(ctx context.Context, rec ml.Recommender, start *ml.Dataset, prefs Preferences, opts Options) (Result, error) {
+	opts = opts.withDefaults()
+	res := Result{Model: rec.Name()}
+	data := start.Clone()
+	tiers := ml.NewPopularityTiers(start, 0, 0)
+
+	users := make([]int, 0, len(data.UserRatings))
+	for u := range data.UserRatings {
+		users = append(users, u)
+	}
+	sort.Ints(users)
+	nActive := max(1, int(math.Round(float64(len(users))*opts.ActiveFraction)))
+
+	now := data.MaxTimestamp()
+	if now == 0 {
+		now = time.Now().Unix()
+	}
+	stepSecs := int64(opts.StepDuration / time.Second)
+
+	filter := ml.FilterFunc(func(user, item int) bool {
+		if opts.Test == nil {
+			return true
+		}
+		_, inTest := opts.Test.UserRatings[user][item]
+		return !inTest
+	})
+
+	for step := 1; step <= opts.Steps; step++ {
+		st := StepStats{Step: step, Day: int(time.Duration(step) * opts.StepDuration / (24 * time.Hour))}
+		if err := rec.Fit(data); err != nil {
+			return res, err
+		}
+		if opts.Test != nil {
+			rr, err := eval.ScoreRating(ctx, rec, opts.Test, opts.Workers)
+			if err != nil {
+				return res, err
+			}
+			st.RMSE, st.MAE = rr.RMSE, rr.MAE
+			rk, err := eval.ScoreRanking(ctx, rec, data, opts.Test, eval.RankingOptions{Cutoffs: []int{opts.K}, Workers: opts.Workers})
+			if err != nil {
+				return res, err
+			}
+			st.Precision, st.Recall, st.NDCG = rk.AtK[0].Precision, rk.AtK[0].Recall, rk.AtK[0].NDCG
+		}
+
+		rng := rand.New(rand.NewSource(opts.Seed + int64(step)))
+		active := append([]int(nil), users...)
+		rng.Shuffle(len(active), func(i, j int) { active[i], active[j] = active[j], active[i] })
+		active = active[:nActive]
+		sort.Ints(active)
+
+		steps := make([]userStep, len(active))
+		errs := make([]error, len(active))
+		forEachUser(ctx, active, opts.Workers, func(i, u int) {
+			recs, err := rec.Recommend(ctx, u, ml.RecommendOptions{TopK: opts.K, Filter: filter})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			var us userStep
+			for pos, it := range recs {
+				r := prefs.Rating(u, it.MovieID)
+				us.shown = append(us.shown, it.MovieID)
+				us.utility += r
+				// dos sorteos fijos por (semilla, paso, usuario, película)
+				look := float64(mix(uint64(opts.Seed), uint64(step), uint64(u), uint64(it.MovieID), 1)>>11) / (1 << 53)
+				take := float64(mix(uint64(opts.Seed), uint64(step), uint64(u), uint64(it.MovieID), 2)>>11) / (1 << 53)
+				if look >= 1/math.Log2(float64(pos)+2) {
+					continue
+				}
+				if take < 1/(1+math.Exp(-(r-opts.Threshold)/opts.Temperature)) {
+					us.accepted = append(us.accepted, it.MovieID)
+					us.ratings = append(us.ratings, math.Round(r*10)/10) // media estrella
+				}
+			}
+			steps[i] = us
+		})
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		for _, err := range errs {
+			if err != nil {
+				return res, err
+			}
+		}
+
+		catalog := itemCounts(data)
+		exposure := make(map[int]int)
+		head := 0
+		ts := now + int64(step)*stepSecs
+		for i, us := range steps {
+			st.Active++
+			st.Shown += len(us.shown)
+			st.Utility += us.utility
+			for _, it := range us.shown {
+				exposure[it]++
+				if tiers.Of(it) == ml.HeadTier {
+					head++
+				}
+			}
+			for j, it := range us.accepted {
+				data.AddRatingAt(active[i], it, us.ratings[j], ts)
+			}
+			st.Accepted += len(us.accepted)
+		}
+		if st.Shown > 0 {
+			st.AcceptRate = float64(st.Accepted) / float64(st.Shown)
+			st.Utility = st.Utility / float64(st.Shown) * 5
+			st.HeadShare = float64(head) / float64(st.Shown)
+		}
+		if len(catalog) > 0 {
+			st.Coverage = float64(len(exposure)) / float64(len(catalog))
+		}
+		st.Gini = eval.Gini(exposure, catalog)
+		counts := itemCounts(data)
+		st.DataGini = eval.Gini(counts, counts)
+		st.Ratings = data.NumRatings()
+		res.Steps = append(res.Steps, st)
+	}
+	return res, nil
+}
+
+// Table arma una tabla con la evolución de cada modelo (una fila por paso)
+func Table(results []Result) eval.Table {
+	t := eval.Table{
+		Title:  "Simulación del ciclo de feedback",
+		Header: []string{"modelo", "paso", "día", "activos", "mostradas", "aceptadas", "tasa", "utilidad", "rmse", "mae", "precision@k", "recall@k", "ndcg@k", "cobertura", "gini", "head", "gini_datos", "ratings"},
+	}
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', 4, 64) }
+	for _, r := range results {
+		for _, s := range r.Steps {
+			t.Rows = append(t.Rows, []string{
+				r.Model,
+				strconv.Itoa(s.Step),
+				strconv.Itoa(s.Day),
+				strconv.Itoa(s.Active),
+				strconv.Itoa(s.Shown),
+				strconv.Itoa(s.Accepted),
+				f(s.AcceptRate),
+				f(s.Utility),
+				f(s.RMSE),
+				f(s.MAE),
+				f(s.Precision),
+				f(s.Recall),
+				f(s.NDCG),
+				f(s.Coverage),
+				f(s.Gini),
+				f(s.HeadShare),
+				f(s.DataGini),
+				strconv.Itoa(s.Ratings),
+			})
+		}
+	}
+	return t
+}
+
+// itemCounts: item -> ratings en el dataset
+func itemCounts(ds *ml.Dataset) map[int]int {
+	counts := make(map[int]int)
+	for _, items := range ds.UserRatings {
+		for it := range items {
+			counts[it]++
+		}
+	}
+	return counts
+}
+
+// forEachUser reparte los usuarios entre workers goroutines (fn recibe el índice y el id)
+func forEachUser(ctx context.Context, users []int, workers int, fn func(i, u int)) {
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i, users[i])
+			}
+		}()
+	}
+	for i := range users {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,172 @@
+package algorithms
+
+import (
+	"sort"
+	"sync"
+)
+
+// CoOccurrence contiene los conteos de juegos jugados juntos
+type CoOccurrence struct {
+	Users   int                 // número de usuarios contados
+	Support map[int]int         // app_id -> usuarios que lo jugaron
+	Pairs   map[int]map[int]int // app_id -> (app_id -> usuarios que jugaron ambos), simétrico
+}
+
+// GameScore asocia un juego con su puntuación de asociación
+type GameScore struct {
+	AppID int
+	Score float64
+}
+
+// CoOccurrenceSequential cuenta co-ocurrencias de juegos de forma secuencial.
+// Juegos y pares con menos de minSupport usuarios se descartan.
+func CoOccurrenceSequential(users []User, minSupport int) *CoOccurrence {
+	support := gameSupport(users, minSupport)
+
+	counts := make(map[int]map[int]int)
+	for _, user := range users {
+		countPairs(user, support, counts)
+	}
+
+	return buildCoOccurrence(len(users), support, counts, minSupport)
+}
+
+// CoOccurrenceConcurrent cuenta co-ocurrencias usando goroutines: cada worker
+// acumula conteos parciales que luego se combinan
+func CoOccurrenceConcurrent(users []User, minSupport int, numWorkers int) *CoOccurrence {
+	support := gameSupport(users, minSupport)
+
+	jobs := make(chan int, min(len(users), numWorkers*100))
+	partials := make(chan map[int]map[int]int, numWorkers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts := make(map[int]map[int]int)
+			for i := range jobs {
+				countPairs(users[i], support, counts)
+			}
+			partials <- counts
+		}()
+	}
+
+	// Enviar trabajos
+	go func() {
+		for i := range users {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	// Cerrar canal de parciales cuando terminen todos los workers
+	go func() {
+		wg.Wait()
+		close(partials)
+	}()
+
+	// Combinar conteos parciales
+	counts := make(map[int]map[int]int)
+	for partial := range partials {
+		for a, row := range partial {
+			if _, exists := counts[a]; !exists {
+				counts[a] = row
+				continue
+			}
+			for b, c := range row {
+				counts[a][b] += c
+			}
+		}
+	}
+
+	return buildCoOccurrence(len(users), support, counts, minSupport)
+}
+
+// FrequentlyPlayedTogether devuelve los k juegos más jugados junto a appID,
+// ordenados por lift (cuánto más probable es jugarlos juntos que por azar)
+func (c *CoOccurrence) FrequentlyPlayedTogether(appID int, k int) []GameScore {
+	var scores []GameScore
+	na := float64(c.Support[appID])
+	for other, nab := range c.Pairs[appID] {
+		nb := float64(c.Support[other])
+		lift := float64(nab) * float64(c.Users) / (na * nb)
+		scores = append(scores, GameScore{AppID: other, Score: lift})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].AppID < scores[j].AppID
+	})
+	if len(scores) > k {
+		scores = scores[:k]
+	}
+	return scores
+}
+
+// gameSupport cuenta cuántos usuarios jugaron cada juego y poda por soporte
+func gameSupport(users []User, minSupport int) map[int]int {
+	support := make(map[int]int)
+	for _, user := range users {
+		for appID := range user.Games {
+			support[appID]++
+		}
+	}
+	for appID, n := range support {
+		if n < minSupport {
+			delete(support, appID)
+		}
+	}
+	return support
+}
+
+// countPairs suma los pares de juegos de un usuario (sólo a < b)
+func countPairs(user User, support map[int]int, counts map[int]map[int]int) {
+	var games []int
+	for appID := range user.Games {
+		if _, exists := support[appID]; exists {
+			games = append(games, appID)
+		}
+	}
+
+	for i := 0; i < len(games); i++ {
+		for j := i + 1; j < len(games); j++ {
+			a, b := games[i], games[j]
+			if a > b {
+				a, b = b, a
+			}
+			if _, exists := counts[a]; !exists {
+				counts[a] = make(map[int]int)
+			}
+			counts[a][b]++
+		}
+	}
+}
+
+// buildCoOccurrence poda los pares por soporte y los guarda en ambas direcciones
+func buildCoOccurrence(numUsers int, support map[int]int, counts map[int]map[int]int, minSupport int) *CoOccurrence {
+	pairs := make(map[int]map[int]int)
+	for a, row := range counts {
+		for b, c := range row {
+			if c < minSupport {
+				continue
+			}
+			if _, exists := pairs[a]; !exists {
+				pairs[a] = make(map[int]int)
+			}
+			if _, exists := pairs[b]; !exists {
+				pairs[b] = make(map[int]int)
+			}
+			pairs[a][b] = c
+			pairs[b][a] = c
+		}
+	}
+
+	return &CoOccurrence{
+		Users:   numUsers,
+		Support: support,
+		Pairs:   pairs,
+	}
+}
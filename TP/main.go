@@ -14,6 +14,9 @@ const (
 	datasetPath = "preprocessing/steam_knn_ready.csv"
 	resultsPath = "results/results_benchmark.csv"
 	resultsDir  = "results"
+
+	// usuarios mínimos para que un juego o par cuente en la co-ocurrencia
+	coOccurrenceMinSupport = 5
 )
 
 func main() {
@@ -114,6 +117,17 @@ func main() {
 			algorithms.JaccardWeightedSequential,
 			algorithms.JaccardWeightedConcurrent)
 
+		// La co-ocurrencia no devuelve una matriz de similitud: se descarta el resultado
+		testAlgorithm("Co-occurrence", users, adjustedWorkers, csvWriter,
+			func(users []algorithms.User) [][]float64 {
+				algorithms.CoOccurrenceSequential(users, coOccurrenceMinSupport)
+				return nil
+			},
+			func(users []algorithms.User, workers int) [][]float64 {
+				algorithms.CoOccurrenceConcurrent(users, coOccurrenceMinSupport, workers)
+				return nil
+			})
+
 		fmt.Println()
 	}
 